@@ -0,0 +1,295 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/huoshan017/ponu/list"
+)
+
+const (
+	arcT1 int8 = iota // 只访问过一次的元素
+	arcT2             // 访问过至少两次的元素
+	arcB1             // 从T1淘汰的key
+	arcB2             // 从T2淘汰的key
+)
+
+type arcNode[K comparable, V any] struct {
+	iter list.IteratorT[Pair[K, V]]
+	seg  int8
+}
+
+type arcGhost[K comparable] struct {
+	iter list.IteratorT[K]
+	seg  int8
+}
+
+// ARC 自适应替换缓存
+// T1和T2分别是只访问过一次和访问过多次的元素的LRU，B1和B2是从它们淘汰的key(不保存value)。
+// 命中B1说明T1太小，命中B2说明T2太小，据此调整T1的目标大小p，
+// 扫描只会进入T1，不会把T2中的热点挤出去
+type ARC[K comparable, V any] struct {
+	statsBase
+	cap        int32
+	p          int32 // T1的目标大小
+	onEvictFun func(K, V, EvictReason)
+	t          [2]list.ListT[Pair[K, V]]
+	b          [2]list.ListT[K]
+	m          map[K]arcNode[K, V]
+	ghosts     map[K]arcGhost[K]
+	pairPool   *list.ListTNodePool[Pair[K, V]]
+	keyPool    *list.ListTNodePool[K]
+}
+
+func NewARC[K comparable, V any](cap int32) *ARC[K, V] {
+	if cap < minCap {
+		cap = minCap
+	}
+	c := &ARC[K, V]{
+		cap:      cap,
+		m:        make(map[K]arcNode[K, V]),
+		ghosts:   make(map[K]arcGhost[K]),
+		pairPool: list.NewListTNodePool[Pair[K, V]](),
+		keyPool:  list.NewListTNodePool[K](),
+	}
+	for i := range c.t {
+		c.t[i] = list.NewListTObjWithPool(c.pairPool)
+		c.b[i] = list.NewListTObjWithPool(c.keyPool)
+	}
+	return c
+}
+
+// WithOnEvict 元素因超出容量、删除、替换被移除时回调
+func (c *ARC[K, V]) WithOnEvict(fun func(K, V, EvictReason)) {
+	c.onEvictFun = fun
+}
+
+func (c *ARC[K, V]) Set(key K, value V) {
+	if n, o := c.m[key]; o {
+		old := n.iter.Value()
+		c.t[n.seg].Delete(n.iter)
+		c.pushBack(arcT2, Pair[K, V]{k: key, v: value})
+		if c.onEvictFun != nil {
+			c.onEvictFun(old.k, old.v, EvictReplaced)
+		}
+		return
+	}
+
+	if g, o := c.ghosts[key]; o {
+		// 命中幽灵列表，调整p后放入T2
+		b1, b2 := c.b[0].GetLength(), c.b[1].GetLength()
+		if g.seg == arcB1 {
+			c.p = min(c.cap, c.p+max(b2/b1, 1))
+		} else {
+			c.p = max(0, c.p-max(b1/b2, 1))
+		}
+		c.deleteGhost(key, g)
+		if c.Len() >= c.cap {
+			c.replace(g.seg == arcB2)
+		}
+		c.pushBack(arcT2, Pair[K, V]{k: key, v: value})
+		return
+	}
+
+	t1, b1 := c.t[0].GetLength(), c.b[0].GetLength()
+	if t1+b1 >= c.cap {
+		if t1 < c.cap {
+			c.popGhost(arcB1)
+			if c.Len() >= c.cap {
+				c.replace(false)
+			}
+		} else {
+			c.evict(arcT1)
+		}
+	} else if total := c.Len() + b1 + c.b[1].GetLength(); total >= c.cap {
+		if total >= 2*c.cap {
+			c.popGhost(arcB2)
+		}
+		if c.Len() >= c.cap {
+			c.replace(false)
+		}
+	}
+	c.pushBack(arcT1, Pair[K, V]{k: key, v: value})
+}
+
+// Get 命中的元素移到T2最近访问的位置
+func (c *ARC[K, V]) Get(key K) (V, bool) {
+	n, o := c.m[key]
+	if !o {
+		c.recordGet(false)
+		var v V
+		return v, false
+	}
+	p := n.iter.Value()
+	if n.seg != arcT2 || n.iter != c.t[1].RBegin() {
+		c.t[n.seg].Delete(n.iter)
+		c.pushBack(arcT2, p)
+	}
+	c.recordGet(true)
+	return p.v, true
+}
+
+// Has 不算作访问
+func (c *ARC[K, V]) Has(key K) bool {
+	_, o := c.m[key]
+	return o
+}
+
+// Delete 幽灵列表中的key也被删除
+func (c *ARC[K, V]) Delete(key K) bool {
+	if g, o := c.ghosts[key]; o {
+		c.deleteGhost(key, g)
+		return false
+	}
+	n, o := c.m[key]
+	if !o {
+		return false
+	}
+	p := n.iter.Value()
+	c.t[n.seg].Delete(n.iter)
+	delete(c.m, key)
+	if c.onEvictFun != nil {
+		c.onEvictFun(p.k, p.v, EvictDeleted)
+	}
+	return true
+}
+
+func (c *ARC[K, V]) Len() int32 {
+	return c.t[0].GetLength() + c.t[1].GetLength()
+}
+
+// Range 先T1后T2，每个列表从最久未访问的开始
+func (c *ARC[K, V]) Range(f func(key K, value V) bool) {
+	for i := range c.t {
+		for iter := c.t[i].Begin(); iter != c.t[i].End(); iter = iter.Next() {
+			p := iter.Value()
+			if !f(p.k, p.v) {
+				return
+			}
+		}
+	}
+}
+
+func (c *ARC[K, V]) Keys() []K {
+	return collectKeys(c.Len(), c.Range)
+}
+
+func (c *ARC[K, V]) Clear() {
+	for i := range c.t {
+		c.t[i].Clear()
+		c.b[i].Clear()
+	}
+	c.m = make(map[K]arcNode[K, V])
+	c.ghosts = make(map[K]arcGhost[K])
+	c.p = 0
+}
+
+func (c *ARC[K, V]) pushBack(seg int8, p Pair[K, V]) {
+	c.t[seg].PushBack(p)
+	c.m[p.k] = arcNode[K, V]{iter: c.t[seg].RBegin(), seg: seg}
+}
+
+func (c *ARC[K, V]) deleteGhost(key K, g arcGhost[K]) {
+	c.b[g.seg-arcB1].Delete(g.iter)
+	delete(c.ghosts, key)
+}
+
+func (c *ARC[K, V]) popGhost(seg int8) {
+	if key, o := c.b[seg-arcB1].PopFront(); o {
+		delete(c.ghosts, key)
+	}
+}
+
+// evict 淘汰T1或T2中最久未访问的元素，不放入幽灵列表
+func (c *ARC[K, V]) evict(seg int8) (Pair[K, V], bool) {
+	p, o := c.t[seg].PopFront()
+	if !o {
+		return p, false
+	}
+	delete(c.m, p.k)
+	c.recordEviction()
+	if c.onEvictFun != nil {
+		c.onEvictFun(p.k, p.v, EvictCapacity)
+	}
+	return p, true
+}
+
+// replace T1超过目标大小时从T1淘汰到B1，否则从T2淘汰到B2
+func (c *ARC[K, V]) replace(inB2 bool) {
+	seg := arcT2
+	if t1 := c.t[0].GetLength(); t1 > 0 && (t1 > c.p || (inB2 && t1 == c.p)) || c.t[1].GetLength() == 0 {
+		seg = arcT1
+	}
+	p, o := c.evict(seg)
+	if !o {
+		return
+	}
+	ghost := seg + arcB1
+	c.b[seg].PushBack(p.k)
+	c.ghosts[p.k] = arcGhost[K]{iter: c.b[seg].RBegin(), seg: ghost}
+}
+
+type ARCWithLock[K comparable, V any] struct {
+	*ARC[K, V]
+	locker sync.Mutex
+}
+
+func NewARCWithLock[K comparable, V any](cap int32) *ARCWithLock[K, V] {
+	return &ARCWithLock[K, V]{
+		ARC: NewARC[K, V](cap),
+	}
+}
+
+func (c *ARCWithLock[K, V]) WithOnEvict(fun func(K, V, EvictReason)) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.ARC.WithOnEvict(fun)
+}
+
+func (c *ARCWithLock[K, V]) Set(key K, value V) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.ARC.Set(key, value)
+}
+
+// Get 访问会移动元素，所以要加写锁
+func (c *ARCWithLock[K, V]) Get(key K) (V, bool) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.ARC.Get(key)
+}
+
+func (c *ARCWithLock[K, V]) Has(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.ARC.Has(key)
+}
+
+func (c *ARCWithLock[K, V]) Delete(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.ARC.Delete(key)
+}
+
+func (c *ARCWithLock[K, V]) Len() int32 {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.ARC.Len()
+}
+
+func (c *ARCWithLock[K, V]) Range(f func(key K, value V) bool) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.ARC.Range(f)
+}
+
+func (c *ARCWithLock[K, V]) Keys() []K {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.ARC.Keys()
+}
+
+func (c *ARCWithLock[K, V]) Clear() {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.ARC.Clear()
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func checkARC(t *testing.T, c *ARC[int, int]) {
+	t1, t2, b1, b2 := c.t[0].GetLength(), c.t[1].GetLength(), c.b[0].GetLength(), c.b[1].GetLength()
+	if t1+t2 > c.cap || t1+b1 > c.cap || t1+t2+b1+b2 > 2*c.cap || c.p < 0 || c.p > c.cap {
+		t.Fatalf("t1 %v t2 %v b1 %v b2 %v p %v", t1, t2, b1, b2, c.p)
+	}
+	if int(t1+t2) != len(c.m) || int(b1+b2) != len(c.ghosts) {
+		t.Fatalf("map size %v %v not match list", len(c.m), len(c.ghosts))
+	}
+	for k := range c.m {
+		if _, o := c.ghosts[k]; o {
+			t.Fatalf("key %v both in cache and ghost", k)
+		}
+	}
+}
+
+func TestARC(t *testing.T) {
+	var (
+		c       = NewARC[int, int](100)
+		r       = rand.New(rand.NewSource(time.Now().UnixNano()))
+		evicted int
+	)
+	c.WithOnEvict(func(k, v int, reason EvictReason) {
+		if reason == EvictCapacity {
+			evicted++
+		}
+		if v != k*2 && reason != EvictReplaced {
+			t.Fatalf("evict key %v value %v", k, v)
+		}
+	})
+	for n := 0; n < 200000; n++ {
+		k := r.Intn(500)
+		if n%2 == 0 {
+			k = r.Intn(50) // 热点
+		}
+		switch r.Intn(10) {
+		case 0:
+			c.Delete(k)
+		case 1, 2, 3:
+			c.Set(k, k*2)
+		default:
+			if v, o := c.Get(k); o && v != k*2 {
+				t.Fatalf("get %v got %v", k, v)
+			}
+		}
+		if n%1000 == 0 {
+			checkARC(t, c)
+		}
+	}
+	checkARC(t, c)
+	if evicted == 0 || c.Len() == 0 {
+		t.Fatalf("evicted %v, length %v", evicted, c.Len())
+	}
+}
+
+func TestARCAdapt(t *testing.T) {
+	c := NewARC[int, int](10)
+	// T1满了且B1为空时直接丢弃，不进入B1
+	for k := 0; k < 20; k++ {
+		c.Set(k, k)
+	}
+	if c.p != 0 || c.b[0].GetLength() != 0 || c.Has(9) || !c.Has(10) {
+		t.Fatalf("p %v, b1 %v", c.p, c.b[0].GetLength())
+	}
+	c.Get(10) // 晋升到T2
+	c.Set(20, 20)
+	if _, o := c.ghosts[11]; !o || c.Has(11) {
+		t.Fatalf("key 11 must be evicted to b1")
+	}
+	// 命中B1，增大T1的目标大小，并放入T2
+	c.Set(11, 11)
+	if c.p != 1 || c.m[11].seg != arcT2 || c.Has(12) {
+		t.Fatalf("p %v after b1 hit", c.p)
+	}
+	c.Set(12, 12)
+	if c.p != 2 || c.t[1].GetLength() != 3 {
+		t.Fatalf("p %v, t2 %v", c.p, c.t[1].GetLength())
+	}
+	checkARC(t, c)
+}
+
+func TestARCRefillAfterDelete(t *testing.T) {
+	c := NewARC[int, int](8)
+	for k := 0; k < 16; k++ {
+		c.Set(k, k)
+	}
+	c.Get(15)
+	c.Set(16, 16) // T1淘汰一个到B1，T1+B1等于容量
+	c.Delete(15)
+	if t1, b1 := c.t[0].GetLength(), c.b[0].GetLength(); t1+b1 != c.cap || c.Len() != c.cap-1 {
+		t.Fatalf("t1 %v b1 %v length %v", t1, b1, c.Len())
+	}
+	// 有空位时未命中的元素直接放入，不淘汰已有的元素
+	for k := 100; k < 120; k++ {
+		c.Set(k, k)
+		if c.Len() != c.cap {
+			t.Fatalf("length %v after set %v", c.Len(), k)
+		}
+		checkARC(t, c)
+	}
+}
+
+func TestARCScanResistant(t *testing.T) {
+	const hotNum = 50
+	var (
+		a   = NewARC[int, int](100)
+		l   = NewLRU[int, int](100)
+		arc = func(k int) bool {
+			if _, o := a.Get(k); o {
+				return true
+			}
+			a.Set(k, k)
+			return false
+		}
+		lru = func(k int) bool {
+			if _, o := l.Get(k); o {
+				return true
+			}
+			l.Set(k, k)
+			return false
+		}
+	)
+	for _, get := range []func(int) bool{arc, lru} {
+		for i := 0; i < 3; i++ {
+			for k := 0; k < hotNum; k++ {
+				get(k)
+			}
+		}
+		// 扫描
+		for k := 1000; k < 1300; k++ {
+			get(k)
+		}
+	}
+	var arcHits, lruHits int
+	for k := 0; k < hotNum; k++ {
+		if a.Has(k) {
+			arcHits++
+		}
+		if l.Has(k) {
+			lruHits++
+		}
+	}
+	if arcHits != hotNum || lruHits != 0 {
+		t.Fatalf("hot keys left after scan: arc %v, lru %v", arcHits, lruHits)
+	}
+	checkARC(t, a)
+}
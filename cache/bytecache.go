@@ -0,0 +1,392 @@
+package cache
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+)
+
+// 元素在环形缓冲区中的布局：
+// expire(8) hash(8) keyLen(2) valueLen(4) flags(1) reserved(1) key value
+// expire是相对于createTime的过期时间点，0表示不过期
+const (
+	byteHeaderSize   = 24
+	byteFlagsOffset  = 22
+	maxByteKeyLen    = math.MaxUint16
+	minByteShardSize = 4096
+)
+
+const (
+	byteFlagDeleted  uint8 = 1 << iota // 已删除，空间在环形缓冲区的头部经过时回收
+	byteFlagAccessed                   // 上次经过头部之后被访问过
+	byteFlagPadding                    // 缓冲区尾部放不下元素时的填充
+)
+
+type byteShard struct {
+	locker sync.Mutex
+	index  map[uint64]uint32 // key的哈希对应元素在buf中的偏移，不含指针，GC不需要扫描
+	buf    []byte
+	head   int // 最旧的元素的偏移
+	tail   int // 下一个写入的偏移
+	used   int // head到tail之间使用的字节数，包括已删除的元素和填充
+	count  int32
+}
+
+func (s *byteShard) entry(off int) (expire int64, hash uint64, keyLen, valueLen int) {
+	b := s.buf[off:]
+	return int64(binary.LittleEndian.Uint64(b)), binary.LittleEndian.Uint64(b[8:]),
+		int(binary.LittleEndian.Uint16(b[16:])), int(binary.LittleEndian.Uint32(b[18:]))
+}
+
+func (s *byteShard) key(off int) []byte {
+	_, _, kl, _ := s.entry(off)
+	return s.buf[off+byteHeaderSize : off+byteHeaderSize+kl]
+}
+
+func (s *byteShard) value(off int) []byte {
+	_, _, kl, vl := s.entry(off)
+	start := off + byteHeaderSize + kl
+	return s.buf[start : start+vl]
+}
+
+func (s *byteShard) find(hash uint64, key string) (int, bool) {
+	off, o := s.index[hash]
+	if !o || string(s.key(int(off))) != key {
+		return 0, false
+	}
+	return int(off), true
+}
+
+// walk 从最旧的元素开始遍历未删除的元素，f返回false时停止
+func (s *byteShard) walk(f func(off int) bool) {
+	for p, n := s.head, s.used; n > 0; {
+		rest := len(s.buf) - p
+		if rest < byteHeaderSize || s.buf[p+byteFlagsOffset]&byteFlagPadding != 0 {
+			n -= rest
+			p = 0
+			continue
+		}
+		_, _, kl, vl := s.entry(p)
+		if s.buf[p+byteFlagsOffset]&byteFlagDeleted == 0 && !f(p) {
+			return
+		}
+		n -= byteHeaderSize + kl + vl
+		if p += byteHeaderSize + kl + vl; p == len(s.buf) {
+			p = 0
+		}
+	}
+}
+
+func (s *byteShard) clear() {
+	s.index = make(map[uint64]uint32)
+	s.head, s.tail, s.used, s.count = 0, 0, 0, 0
+}
+
+// ByteCache key为字符串、value为字节数组的缓存，元素保存在每个分片预先分配的环形缓冲区中，
+// 索引是不含指针的map，大量的小元素不会增加GC扫描的负担
+// 空间不够时从最旧的元素开始淘汰，被访问过的元素有一次重新放到尾部的机会，近似于LRU
+// 线程安全，每个分片加锁访问
+type ByteCache struct {
+	statsBase
+	hasher      func(string) uint64
+	mask        uint64
+	expiredTime time.Duration                     // 默认的过期时间，0表示不过期
+	createTime  time.Time                         // 创建时间
+	onEvictFun  func(string, []byte, EvictReason) // 元素被移除时回调
+	reaper      reaper                            // 后台回收过期元素
+	shards      []byteShard
+}
+
+// NewByteCache maxBytes是所有分片的环形缓冲区的总大小，创建时一次性分配
+// shardNum向上取整为2的幂，小于等于0时使用默认的64
+func NewByteCache(shardNum int, maxBytes int64) *ByteCache {
+	n := roundShardNum(shardNum)
+	shardSize := maxBytes / int64(n)
+	if shardSize < minByteShardSize {
+		shardSize = minByteShardSize
+	}
+	if shardSize > math.MaxUint32 {
+		shardSize = math.MaxUint32
+	}
+	c := &ByteCache{
+		hasher:     NewHasher[string](),
+		mask:       uint64(n - 1),
+		createTime: time.Now(),
+		shards:     make([]byteShard, n),
+	}
+	for i := range c.shards {
+		c.shards[i].buf = make([]byte, shardSize)
+		c.shards[i].index = make(map[uint64]uint32)
+	}
+	return c
+}
+
+// WithOnEvict 元素因过期、超出容量、删除、替换被移除时回调，回调在分片加锁时执行
+// key和value引用的是缓冲区中的数据，只在回调中有效
+func (c *ByteCache) WithOnEvict(fun func(key string, value []byte, reason EvictReason)) {
+	c.onEvictFun = fun
+}
+
+// WithExpiredtime 设置默认的过期时间，对之后Set的元素生效
+func (c *ByteCache) WithExpiredtime(t time.Duration) {
+	if t < minExpiredTime {
+		t = minExpiredTime
+	}
+	c.expiredTime = t
+}
+
+// StartReaper 用scheduler每隔interval回收一次过期的元素，可以在任意协程中执行定时器回调
+func (c *ByteCache) StartReaper(scheduler Scheduler, interval time.Duration) bool {
+	return c.reaper.start(scheduler, interval, func() { c.DeleteExpired() })
+}
+
+// StopReaper ...
+func (c *ByteCache) StopReaper() {
+	c.reaper.stop()
+}
+
+func (c *ByteCache) getShard(hash uint64) *byteShard {
+	return &c.shards[hash&c.mask]
+}
+
+func (c *ByteCache) now() int64 {
+	return int64(time.Since(c.createTime))
+}
+
+func (c *ByteCache) isExpired(s *byteShard, off int, now int64) bool {
+	e, _, _, _ := s.entry(off)
+	return e > 0 && now >= e
+}
+
+// Set key的长度超过65535或者元素超出分片缓冲区的大小时不缓存
+func (c *ByteCache) Set(key string, value []byte) {
+	c.set(key, value, c.expiredTime)
+}
+
+// SetExpired 设置元素并指定过期时间
+func (c *ByteCache) SetExpired(key string, value []byte, expiredTime time.Duration) {
+	if expiredTime <= 0 {
+		expiredTime = minExpiredTime
+	}
+	c.set(key, value, expiredTime)
+}
+
+func (c *ByteCache) set(key string, value []byte, expiredTime time.Duration) {
+	if len(key) > maxByteKeyLen {
+		return
+	}
+	var (
+		hash = c.hasher(key)
+		s    = c.getShard(hash)
+		size = byteHeaderSize + len(key) + len(value)
+	)
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	// 已有的相同key或者哈希冲突的元素都被替换
+	if off, o := s.index[hash]; o {
+		c.remove(s, int(off), EvictReplaced)
+	}
+	if size > len(s.buf) {
+		return
+	}
+	off := c.alloc(s, size)
+	var e int64
+	if expiredTime > 0 {
+		e = c.now() + int64(expiredTime)
+	}
+	b := s.buf[off:]
+	binary.LittleEndian.PutUint64(b, uint64(e))
+	binary.LittleEndian.PutUint64(b[8:], hash)
+	binary.LittleEndian.PutUint16(b[16:], uint16(len(key)))
+	binary.LittleEndian.PutUint32(b[18:], uint32(len(value)))
+	b[byteFlagsOffset] = 0
+	copy(b[byteHeaderSize:], key)
+	copy(b[byteHeaderSize+len(key):], value)
+	s.index[hash] = uint32(off)
+	s.tail = off + size
+	s.used += size
+	s.count++
+}
+
+// alloc 在尾部找到size字节的连续空间，不够时从头部淘汰，size不能超过缓冲区的大小
+func (c *ByteCache) alloc(s *byteShard, size int) int {
+	for {
+		if s.used == 0 {
+			s.head, s.tail = 0, 0
+		}
+		if s.tail > s.head || s.used == 0 {
+			if len(s.buf)-s.tail >= size {
+				return s.tail
+			}
+			// 尾部放不下，填充后从缓冲区开始的位置写入
+			if len(s.buf)-s.tail >= byteHeaderSize {
+				s.buf[s.tail+byteFlagsOffset] = byteFlagPadding
+			}
+			s.used += len(s.buf) - s.tail
+			s.tail = 0
+			continue
+		}
+		if s.head-s.tail >= size {
+			return s.tail
+		}
+		c.evictHead(s)
+	}
+}
+
+// evictHead 回收头部的元素，被访问过且没过期的元素移到尾部，只在尾部在头部之前时调用
+func (c *ByteCache) evictHead(s *byteShard) {
+	rest := len(s.buf) - s.head
+	if rest < byteHeaderSize || s.buf[s.head+byteFlagsOffset]&byteFlagPadding != 0 {
+		s.used -= rest
+		s.head = 0
+		return
+	}
+	_, hash, kl, vl := s.entry(s.head)
+	size := byteHeaderSize + kl + vl
+	if flags := s.buf[s.head+byteFlagsOffset]; flags&byteFlagDeleted == 0 {
+		expired := c.isExpired(s, s.head, c.now())
+		if flags&byteFlagAccessed != 0 && !expired {
+			s.buf[s.head+byteFlagsOffset] &^= byteFlagAccessed
+			copy(s.buf[s.tail:], s.buf[s.head:s.head+size])
+			s.index[hash] = uint32(s.tail)
+			s.tail += size
+			s.used += size
+		} else if expired {
+			c.remove(s, s.head, EvictExpired)
+		} else {
+			c.remove(s, s.head, EvictCapacity)
+		}
+	}
+	s.used -= size
+	if s.head += size; s.head == len(s.buf) {
+		s.head = 0
+	}
+}
+
+// remove 标记为已删除，空间之后回收
+func (c *ByteCache) remove(s *byteShard, off int, reason EvictReason) {
+	_, hash, _, _ := s.entry(off)
+	s.buf[off+byteFlagsOffset] |= byteFlagDeleted
+	delete(s.index, hash)
+	s.count--
+	if reason == EvictExpired || reason == EvictCapacity {
+		c.recordEviction()
+	}
+	if c.onEvictFun != nil {
+		c.onEvictFun(string(s.key(off)), s.value(off), reason)
+	}
+}
+
+// Get 返回value的拷贝
+func (c *ByteCache) Get(key string) ([]byte, bool) {
+	hash := c.hasher(key)
+	s := c.getShard(hash)
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	off, o := s.find(hash, key)
+	if !o {
+		c.recordGet(false)
+		return nil, false
+	}
+	if c.isExpired(s, off, c.now()) {
+		c.remove(s, off, EvictExpired)
+		c.recordGet(false)
+		return nil, false
+	}
+	s.buf[off+byteFlagsOffset] |= byteFlagAccessed
+	c.recordGet(true)
+	return append([]byte(nil), s.value(off)...), true
+}
+
+// Has 不算作访问，过期的元素会被删除
+func (c *ByteCache) Has(key string) bool {
+	hash := c.hasher(key)
+	s := c.getShard(hash)
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	off, o := s.find(hash, key)
+	if o && c.isExpired(s, off, c.now()) {
+		c.remove(s, off, EvictExpired)
+		return false
+	}
+	return o
+}
+
+func (c *ByteCache) Delete(key string) bool {
+	hash := c.hasher(key)
+	s := c.getShard(hash)
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	off, o := s.find(hash, key)
+	if !o {
+		return false
+	}
+	c.remove(s, off, EvictDeleted)
+	return true
+}
+
+// DeleteExpired 删除所有过期的元素，返回删除的个数
+func (c *ByteCache) DeleteExpired() int32 {
+	var num int32
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.locker.Lock()
+		now := c.now()
+		s.walk(func(off int) bool {
+			if c.isExpired(s, off, now) {
+				c.remove(s, off, EvictExpired)
+				num++
+			}
+			return true
+		})
+		s.locker.Unlock()
+	}
+	return num
+}
+
+// Len 元素个数，包括已过期但还没被删除的
+func (c *ByteCache) Len() int32 {
+	var n int32
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.locker.Lock()
+		n += s.count
+		s.locker.Unlock()
+	}
+	return n
+}
+
+// Range 逐个分片加锁从最旧的元素开始遍历，value只在f中有效
+func (c *ByteCache) Range(f func(key string, value []byte) bool) {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.locker.Lock()
+		goon, now := true, c.now()
+		s.walk(func(off int) bool {
+			if c.isExpired(s, off, now) {
+				return true
+			}
+			goon = f(string(s.key(off)), s.value(off))
+			return goon
+		})
+		s.locker.Unlock()
+		if !goon {
+			return
+		}
+	}
+}
+
+func (c *ByteCache) Keys() []string {
+	return collectKeys(c.Len(), c.Range)
+}
+
+// Clear 清空所有元素，缓冲区保留
+func (c *ByteCache) Clear() {
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.locker.Lock()
+		s.clear()
+		s.locker.Unlock()
+	}
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// checkByteShard 检查索引、计数和环形缓冲区中的元素是否一致
+func checkByteShard(t *testing.T, s *byteShard) {
+	var n int32
+	s.walk(func(off int) bool {
+		_, hash, _, _ := s.entry(off)
+		if o, has := s.index[hash]; !has || int(o) != off {
+			t.Fatalf("entry at %v not indexed", off)
+		}
+		n++
+		return true
+	})
+	if n != s.count || int(n) != len(s.index) {
+		t.Fatalf("walk %v entries, count %v, index %v", n, s.count, len(s.index))
+	}
+	if s.used < 0 || s.used > len(s.buf) {
+		t.Fatalf("used %v", s.used)
+	}
+}
+
+func TestByteCache(t *testing.T) {
+	var (
+		c       = NewByteCache(1, minByteShardSize)
+		model   = make(map[string][]byte)
+		r       = rand.New(rand.NewSource(time.Now().UnixNano()))
+		evicted = make(map[EvictReason]int)
+	)
+	c.WithOnEvict(func(key string, value []byte, reason EvictReason) {
+		if reason == EvictCapacity || reason == EvictExpired {
+			delete(model, key)
+		}
+		evicted[reason]++
+	})
+	for n := 0; n < 100000; n++ {
+		k := fmt.Sprintf("key%v", r.Intn(300))
+		switch r.Intn(5) {
+		case 0:
+			if c.Delete(k) != (model[k] != nil) {
+				t.Fatalf("delete %v not match", k)
+			}
+			delete(model, k)
+		case 1, 2:
+			v := bytes.Repeat([]byte{byte(n)}, r.Intn(200)+1)
+			c.Set(k, v)
+			model[k] = v
+		default:
+			v, o := c.Get(k)
+			if o != (model[k] != nil) || !bytes.Equal(v, model[k]) {
+				t.Fatalf("get %v got %v %v, expect %v", k, v, o, model[k])
+			}
+		}
+		if n%1000 == 0 {
+			checkByteShard(t, &c.shards[0])
+		}
+	}
+	checkByteShard(t, &c.shards[0])
+	if c.Len() != int32(len(model)) || len(c.Keys()) != len(model) {
+		t.Fatalf("length %v, expect %v", c.Len(), len(model))
+	}
+	if evicted[EvictCapacity] == 0 || evicted[EvictReplaced] == 0 || evicted[EvictDeleted] == 0 {
+		t.Fatalf("evicted %v", evicted)
+	}
+
+	// 超出缓冲区的元素不缓存，并删除原来的值
+	c.Set("big", []byte("v"))
+	if c.Set("big", make([]byte, minByteShardSize)); c.Has("big") {
+		t.Fatalf("too big value cached")
+	}
+	c.Clear()
+	if c.Len() != 0 || c.Has("key1") {
+		t.Fatalf("length %v after clear", c.Len())
+	}
+}
+
+func TestByteCacheSecondChance(t *testing.T) {
+	c := NewByteCache(1, minByteShardSize)
+	value := make([]byte, 100-byteHeaderSize-6)
+	for i := 0; i < 40; i++ {
+		c.Set(fmt.Sprintf("key%03d", i), value)
+	}
+	c.Get("key000")
+	// 缓冲区可以放40个，再放入10个淘汰最旧的，被访问过的key000移到尾部
+	for i := 40; i < 50; i++ {
+		c.Set(fmt.Sprintf("key%03d", i), value)
+	}
+	if !c.Has("key000") || c.Has("key001") || c.Has("key010") || !c.Has("key011") {
+		t.Fatalf("keys %v", c.Keys())
+	}
+	if keys := c.Keys(); keys[len(keys)-1] != "key049" || c.Len() != 40 {
+		t.Fatalf("keys %v", keys)
+	}
+	checkByteShard(t, &c.shards[0])
+}
+
+func TestByteCacheExpire(t *testing.T) {
+	var (
+		c       = NewByteCache(4, 0)
+		expired []string
+	)
+	c.WithOnEvict(func(key string, value []byte, reason EvictReason) {
+		if reason == EvictExpired {
+			expired = append(expired, key)
+		}
+	})
+	c.WithStats()
+	for i := 0; i < 10; i++ {
+		c.SetExpired(fmt.Sprint(i), []byte("v"), 30*time.Millisecond)
+	}
+	c.Set("keep", []byte("v"))
+	time.Sleep(40 * time.Millisecond)
+	if _, o := c.Get("0"); o || c.Has("1") || len(expired) != 2 {
+		t.Fatalf("expired key still exists, expired %v", expired)
+	}
+	if n := c.DeleteExpired(); n != 8 || c.Len() != 1 || len(expired) != 10 {
+		t.Fatalf("delete expired %v, length %v", n, c.Len())
+	}
+	if s := c.Stats(); s.Misses != 1 || s.Evictions != 10 {
+		t.Fatalf("stats %+v", s)
+	}
+}
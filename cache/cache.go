@@ -0,0 +1,24 @@
+package cache
+
+// Cache 缓存的通用接口，所有的缓存类型都实现了该接口，Sharded的每个shard可以是任意实现了该接口的缓存
+type Cache[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Delete(key K) bool
+	Has(key K) bool
+	Len() int32
+	Clear()
+	// Range 遍历未过期的元素，不影响淘汰顺序，f返回false时停止，f中不能修改缓存
+	Range(f func(key K, value V) bool)
+	// Keys 未过期元素的key，顺序和Range相同
+	Keys() []K
+}
+
+func collectKeys[K comparable, V any](n int32, rangeFun func(func(K, V) bool)) []K {
+	keys := make([]K, 0, n)
+	rangeFun(func(k K, _ V) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
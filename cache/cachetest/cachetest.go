@@ -0,0 +1,269 @@
+// Package cachetest cache.Cache接口的一致性测试和基准测试，所有的缓存实现包括以后新加的都需要通过
+package cachetest
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/huoshan017/ponu/cache"
+)
+
+// Capacity 测试使用的缓存容量
+const Capacity = 1024
+
+// NewCache 创建元素个数不超过cap的缓存
+type NewCache func(cap int32) cache.Cache[int, int]
+
+func valueOf(key int) int {
+	return key*2 + 1
+}
+
+// Run 一致性测试，不要求缓存是线程安全的
+func Run(t *testing.T, newCache NewCache) {
+	t.Run("Empty", func(t *testing.T) { testEmpty(t, newCache(Capacity)) })
+	t.Run("SetGet", func(t *testing.T) { testSetGet(t, newCache(Capacity)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newCache(Capacity)) })
+	t.Run("Capacity", func(t *testing.T) { testCapacity(t, newCache(Capacity)) })
+	t.Run("Range", func(t *testing.T) { testRange(t, newCache(Capacity)) })
+	t.Run("Clear", func(t *testing.T) { testClear(t, newCache(Capacity)) })
+}
+
+func testEmpty(t *testing.T, c cache.Cache[int, int]) {
+	if _, o := c.Get(1); o || c.Has(1) || c.Delete(1) {
+		t.Fatalf("empty cache has key")
+	}
+	if c.Len() != 0 || len(c.Keys()) != 0 {
+		t.Fatalf("empty cache length %v, keys %v", c.Len(), c.Keys())
+	}
+	c.Range(func(k, v int) bool {
+		t.Fatalf("empty cache range got %v", k)
+		return false
+	})
+}
+
+func testSetGet(t *testing.T, c cache.Cache[int, int]) {
+	for k := 0; k < 10; k++ {
+		c.Set(k, valueOf(k))
+		if v, o := c.Get(k); !o || v != valueOf(k) {
+			t.Fatalf("get %v got %v %v", k, v, o)
+		}
+		if !c.Has(k) {
+			t.Fatalf("has no key %v after set", k)
+		}
+	}
+	if c.Len() != 10 {
+		t.Fatalf("length %v", c.Len())
+	}
+	// 覆盖已有的key不改变元素个数
+	c.Set(3, 100)
+	if v, o := c.Get(3); !o || v != 100 || c.Len() != 10 {
+		t.Fatalf("overwrite got %v %v, length %v", v, o, c.Len())
+	}
+	if _, o := c.Get(10); o || c.Has(10) {
+		t.Fatalf("get not existed key")
+	}
+}
+
+func testDelete(t *testing.T, c cache.Cache[int, int]) {
+	for k := 0; k < 10; k++ {
+		c.Set(k, valueOf(k))
+	}
+	if !c.Delete(5) || c.Delete(5) {
+		t.Fatalf("delete must succeed only once")
+	}
+	if _, o := c.Get(5); o || c.Has(5) || c.Len() != 9 {
+		t.Fatalf("deleted key still exists")
+	}
+	for _, k := range c.Keys() {
+		if k == 5 {
+			t.Fatalf("keys contain deleted key")
+		}
+	}
+	c.Set(5, 1)
+	if v, o := c.Get(5); !o || v != 1 {
+		t.Fatalf("set after delete got %v %v", v, o)
+	}
+}
+
+func testCapacity(t *testing.T, c cache.Cache[int, int]) {
+	for k := 0; k < Capacity*10; k++ {
+		c.Set(k, valueOf(k))
+		if c.Len() > Capacity {
+			t.Fatalf("length %v exceeds capacity after set %v", c.Len(), k)
+		}
+	}
+	if c.Len() == 0 {
+		t.Fatalf("cache is empty after set")
+	}
+	last := Capacity*10 - 1
+	if v, o := c.Get(last); !o || v != valueOf(last) {
+		t.Fatalf("last set key %v got %v %v", last, v, o)
+	}
+	c.Range(func(k, v int) bool {
+		if v != valueOf(k) {
+			t.Fatalf("key %v got value %v", k, v)
+		}
+		return true
+	})
+}
+
+func testRange(t *testing.T, c cache.Cache[int, int]) {
+	for k := 0; k < 100; k++ {
+		c.Set(k, valueOf(k))
+	}
+	for k := 0; k < 100; k += 3 {
+		c.Get(k)
+	}
+	keys := c.Keys()
+	if len(keys) != int(c.Len()) {
+		t.Fatalf("keys length %v, cache length %v", len(keys), c.Len())
+	}
+	seen := make(map[int]bool)
+	i := 0
+	c.Range(func(k, v int) bool {
+		if seen[k] {
+			t.Fatalf("range got duplicate key %v", k)
+		}
+		seen[k] = true
+		if v != valueOf(k) {
+			t.Fatalf("range key %v got value %v", k, v)
+		}
+		if keys[i] != k {
+			t.Fatalf("range order differs from keys at %v", i)
+		}
+		i++
+		return true
+	})
+	if len(seen) != len(keys) {
+		t.Fatalf("range visited %v, expect %v", len(seen), len(keys))
+	}
+	// 遍历不影响淘汰顺序
+	again := c.Keys()
+	for i := range keys {
+		if keys[i] != again[i] {
+			t.Fatalf("range changed order at %v", i)
+		}
+	}
+	n := 0
+	c.Range(func(k, v int) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Fatalf("range not stopped, visited %v", n)
+	}
+}
+
+func testClear(t *testing.T, c cache.Cache[int, int]) {
+	for k := 0; k < 100; k++ {
+		c.Set(k, valueOf(k))
+	}
+	c.Clear()
+	if c.Len() != 0 || len(c.Keys()) != 0 || c.Has(1) {
+		t.Fatalf("length %v after clear", c.Len())
+	}
+	c.Set(1, valueOf(1))
+	if v, o := c.Get(1); !o || v != valueOf(1) || c.Len() != 1 {
+		t.Fatalf("set after clear got %v %v", v, o)
+	}
+}
+
+// RunConcurrent 线程安全的缓存在Run之外还需要通过的并发测试，配合-race使用
+func RunConcurrent(t *testing.T, newCache NewCache) {
+	var (
+		c  = newCache(Capacity)
+		wg sync.WaitGroup
+	)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g)))
+			for n := 0; n < 20000; n++ {
+				k := r.Intn(Capacity * 4)
+				switch r.Intn(10) {
+				case 0:
+					c.Delete(k)
+				case 1, 2, 3:
+					c.Set(k, valueOf(k))
+				case 4:
+					c.Has(k)
+				case 5:
+					if n%100 == 0 {
+						c.Range(func(k, v int) bool {
+							if v != valueOf(k) {
+								t.Errorf("range key %v got value %v", k, v)
+								return false
+							}
+							return true
+						})
+					}
+				default:
+					if v, o := c.Get(k); o && v != valueOf(k) {
+						t.Errorf("get key %v got value %v", k, v)
+						return
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if c.Len() > Capacity {
+		t.Fatalf("length %v exceeds capacity", c.Len())
+	}
+}
+
+// Bench 基准测试，key的访问符合zipf分布
+func Bench(b *testing.B, newCache NewCache) {
+	keys := make([]int, 1<<16)
+	z := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, Capacity*16)
+	for i := range keys {
+		keys[i] = int(z.Uint64())
+	}
+
+	b.Run("Set", func(b *testing.B) {
+		c := newCache(Capacity)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			k := keys[i&(len(keys)-1)]
+			c.Set(k, k)
+		}
+	})
+	b.Run("GetHit", func(b *testing.B) {
+		c := newCache(Capacity)
+		for k := 0; k < Capacity; k++ {
+			c.Set(k, k)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			c.Get(i & (Capacity - 1))
+		}
+	})
+	b.Run("Mixed", func(b *testing.B) {
+		c := newCache(Capacity)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			k := keys[i&(len(keys)-1)]
+			if _, o := c.Get(k); !o {
+				c.Set(k, k)
+			}
+		}
+	})
+}
+
+// BenchParallel 线程安全的缓存的并发基准测试
+func BenchParallel(b *testing.B, newCache NewCache) {
+	c := newCache(Capacity)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		z := rand.NewZipf(rand.New(rand.NewSource(rand.Int63())), 1.1, 1, Capacity*16)
+		for pb.Next() {
+			k := int(z.Uint64())
+			if _, o := c.Get(k); !o {
+				c.Set(k, k)
+			}
+		}
+	})
+}
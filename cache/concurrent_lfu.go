@@ -2,6 +2,8 @@ package cache
 
 import (
 	"reflect"
+	"sync/atomic"
+	"time"
 
 	"github.com/huoshan017/ponu/list"
 )
@@ -16,7 +18,8 @@ type keyType interface {
 
 type ConcurrentLFU[K keyType, V any] struct {
 	typ      reflect.Type
-	currSize int32
+	currSize int64
+	recorder StatsRecorder // 所有shard共享的统计记录器，为nil时各个shard分别统计
 	shards   []*LFUWithLock[K, V]
 }
 
@@ -49,6 +52,67 @@ func NewConcurrentLFU[K keyType, V any](cap int32) *ConcurrentLFU[K, V] {
 	return lfu
 }
 
+// WithWeigher 所有shard共享maxWeight的总权重，需要在使用前设置
+func (lfu *ConcurrentLFU[K, V]) WithWeigher(weigher func(K, V) int64, maxWeight int64) {
+	for i := 0; i < len(lfu.shards); i++ {
+		lfu.shards[i].WithWeigher(weigher, maxWeight)
+	}
+}
+
+// WithHalvingEveryOps 每个shard各自计数，每n次操作后该shard的访问频次减半
+func (lfu *ConcurrentLFU[K, V]) WithHalvingEveryOps(n int32) {
+	for i := 0; i < len(lfu.shards); i++ {
+		lfu.shards[i].WithHalvingEveryOps(n)
+	}
+}
+
+// WithHalvingPeriod ...
+func (lfu *ConcurrentLFU[K, V]) WithHalvingPeriod(d time.Duration) {
+	for i := 0; i < len(lfu.shards); i++ {
+		lfu.shards[i].WithHalvingPeriod(d)
+	}
+}
+
+// WithDynamicAging 每个shard有各自的缓存年龄
+func (lfu *ConcurrentLFU[K, V]) WithDynamicAging() {
+	for i := 0; i < len(lfu.shards); i++ {
+		lfu.shards[i].WithDynamicAging()
+	}
+}
+
+// WithStats 每个shard使用各自的统计记录器，Stats时汇总
+func (lfu *ConcurrentLFU[K, V]) WithStats() {
+	lfu.recorder = nil
+	for i := 0; i < len(lfu.shards); i++ {
+		lfu.shards[i].WithStats()
+	}
+}
+
+// WithStatsRecorder 所有shard共享同一个统计记录器
+func (lfu *ConcurrentLFU[K, V]) WithStatsRecorder(recorder StatsRecorder) {
+	lfu.recorder = recorder
+	for i := 0; i < len(lfu.shards); i++ {
+		lfu.shards[i].WithStatsRecorder(recorder)
+	}
+}
+
+// Stats 所有shard汇总的统计
+func (lfu *ConcurrentLFU[K, V]) Stats() Stats {
+	if lfu.recorder != nil {
+		return lfu.recorder.Snapshot()
+	}
+	var s Stats
+	for i := 0; i < len(lfu.shards); i++ {
+		s = s.Add(lfu.shards[i].Stats())
+	}
+	return s
+}
+
+// GetWeight 所有shard的总权重
+func (lfu *ConcurrentLFU[K, V]) GetWeight() int64 {
+	return atomic.LoadInt64(&lfu.currSize)
+}
+
 func (lfu *ConcurrentLFU[K, V]) Set(key K, value V) {
 	index := lfu.getHashIndex(key)
 	shard := lfu.shards[index]
@@ -73,6 +137,33 @@ func (lfu *ConcurrentLFU[K, V]) Delete(key K) bool {
 	return shard.Delete(key)
 }
 
+// Len 所有shard的元素个数之和
+func (lfu *ConcurrentLFU[K, V]) Len() int32 {
+	var n int32
+	for i := 0; i < len(lfu.shards); i++ {
+		n += lfu.shards[i].Len()
+	}
+	return n
+}
+
+// Range 逐个shard加锁遍历
+func (lfu *ConcurrentLFU[K, V]) Range(f func(key K, value V) bool) {
+	for i := 0; i < len(lfu.shards); i++ {
+		goon := true
+		lfu.shards[i].Range(func(k K, v V) bool {
+			goon = f(k, v)
+			return goon
+		})
+		if !goon {
+			return
+		}
+	}
+}
+
+func (lfu *ConcurrentLFU[K, V]) Keys() []K {
+	return collectKeys(lfu.Len(), lfu.Range)
+}
+
 func (lfu *ConcurrentLFU[K, V]) Clear() {
 	for i := 0; i < len(lfu.shards); i++ {
 		lfu.shards[i].Clear()
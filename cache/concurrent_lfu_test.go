@@ -68,7 +68,7 @@ func TestConcurrentLFU(t *testing.T) {
 
 	wg.Wait()
 
-	if l.currSize > cap {
+	if l.currSize > int64(cap) {
 		t.Fatalf("curr size %v cant greater to cap %v", l.currSize, cap)
 	}
 
@@ -0,0 +1,107 @@
+package cache_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/huoshan017/ponu/cache"
+	"github.com/huoshan017/ponu/cache/cachetest"
+)
+
+type implementation struct {
+	name       string
+	newCache   cachetest.NewCache
+	concurrent bool
+}
+
+func shardedLRU(cap int32) cache.Cache[int, int] {
+	return cache.NewSharded(4, func() cache.Cache[int, int] { return cache.NewLRU[int, int](cap / 4).AsCache() })
+}
+
+func shardedTinyLFU(cap int32) cache.Cache[int, int] {
+	return cache.NewSharded(4, func() cache.Cache[int, int] { return cache.NewTinyLFU[int, int](cap / 4) })
+}
+
+// byteCache 把ByteCache适配为Cache[int, int]，key和value都是8字节，每个元素占40字节
+type byteCache struct {
+	c *cache.ByteCache
+}
+
+func newByteCache(cap int32) cache.Cache[int, int] {
+	return byteCache{c: cache.NewByteCache(4, int64(cap)*40)}
+}
+
+func byteKey(k int) string {
+	return string(binary.LittleEndian.AppendUint64(nil, uint64(k)))
+}
+
+func (b byteCache) Get(key int) (int, bool) {
+	v, o := b.c.Get(byteKey(key))
+	if !o {
+		return 0, false
+	}
+	return int(binary.LittleEndian.Uint64(v)), true
+}
+
+func (b byteCache) Set(key int, value int) {
+	b.c.Set(byteKey(key), binary.LittleEndian.AppendUint64(nil, uint64(value)))
+}
+
+func (b byteCache) Delete(key int) bool { return b.c.Delete(byteKey(key)) }
+func (b byteCache) Has(key int) bool    { return b.c.Has(byteKey(key)) }
+func (b byteCache) Len() int32          { return b.c.Len() }
+func (b byteCache) Clear()              { b.c.Clear() }
+
+func (b byteCache) Range(f func(key int, value int) bool) {
+	b.c.Range(func(k string, v []byte) bool {
+		return f(int(binary.LittleEndian.Uint64([]byte(k))), int(binary.LittleEndian.Uint64(v)))
+	})
+}
+
+func (b byteCache) Keys() []int {
+	var keys []int
+	b.Range(func(k, _ int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+var _ cache.Cache[string, []byte] = (*cache.ByteCache)(nil)
+
+var implementations = []implementation{
+	{"LRU", func(cap int32) cache.Cache[int, int] { return cache.NewLRU[int, int](cap).AsCache() }, false},
+	{"LRUWithLock", func(cap int32) cache.Cache[int, int] { return cache.NewLRUWithLock[int, int](cap) }, true},
+	{"LFU", func(cap int32) cache.Cache[int, int] { return cache.NewLFU[int, int](cap) }, false},
+	{"LFUWithLock", func(cap int32) cache.Cache[int, int] { return cache.NewLFUWithLock[int, int](cap) }, true},
+	{"ConcurrentLFU", func(cap int32) cache.Cache[int, int] { return cache.NewConcurrentLFU[int, int](cap / 64) }, true},
+	{"TinyLFU", func(cap int32) cache.Cache[int, int] { return cache.NewTinyLFU[int, int](cap) }, false},
+	{"TinyLFUWithLock", func(cap int32) cache.Cache[int, int] { return cache.NewTinyLFUWithLock[int, int](cap) }, true},
+	{"ARC", func(cap int32) cache.Cache[int, int] { return cache.NewARC[int, int](cap) }, false},
+	{"ARCWithLock", func(cap int32) cache.Cache[int, int] { return cache.NewARCWithLock[int, int](cap) }, true},
+	{"ShardedLRU", shardedLRU, true},
+	{"ShardedTinyLFU", shardedTinyLFU, true},
+	{"ByteCache", newByteCache, true},
+}
+
+func TestConformance(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			cachetest.Run(t, impl.newCache)
+			if impl.concurrent {
+				t.Run("Concurrent", func(t *testing.T) { cachetest.RunConcurrent(t, impl.newCache) })
+			}
+		})
+	}
+}
+
+func BenchmarkCache(b *testing.B) {
+	for _, impl := range implementations {
+		b.Run(impl.name, func(b *testing.B) {
+			cachetest.Bench(b, impl.newCache)
+			if impl.concurrent {
+				b.Run("Parallel", func(b *testing.B) { cachetest.BenchParallel(b, impl.newCache) })
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// NewHasher 返回K的默认哈希函数，基于maphash，每次调用使用不同的随机种子
+// 整数和字符串类型(包括以它们为底层类型的类型)直接计算，其他可比较的类型通过反射逐个字段计算
+func NewHasher[K comparable]() func(K) uint64 {
+	var (
+		k    K
+		seed = maphash.MakeSeed()
+	)
+	t := reflect.TypeOf(k)
+	if t == nil { // K是接口类型
+		return func(key K) uint64 {
+			var h maphash.Hash
+			h.SetSeed(seed)
+			hashValue(&h, reflect.ValueOf(&key).Elem())
+			return h.Sum64()
+		}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return func(key K) uint64 {
+			return maphash.String(seed, *(*string)(unsafe.Pointer(&key)))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		size := int(t.Size())
+		return func(key K) uint64 {
+			return maphash.Bytes(seed, unsafe.Slice((*byte)(unsafe.Pointer(&key)), size))
+		}
+	}
+	return func(key K) uint64 {
+		var h maphash.Hash
+		h.SetSeed(seed)
+		hashValue(&h, reflect.ValueOf(key))
+		return h.Sum64()
+	}
+}
+
+// hashValue 相等的值得到相同的哈希，+0和-0相等，NaN和任何值都不相等所以不用特殊处理
+func hashValue(h *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
+	writeUint := func(u uint64) {
+		binary.LittleEndian.PutUint64(buf[:], u)
+		h.Write(buf[:])
+	}
+	writeFloat := func(f float64) {
+		if f == 0 {
+			f = 0
+		}
+		writeUint(math.Float64bits(f))
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFloat(real(c))
+		writeFloat(imag(c))
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		writeUint(uint64(v.Pointer()))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			hashValue(h, v.Field(i))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			h.WriteByte(0)
+			return
+		}
+		e := v.Elem()
+		h.WriteString(e.Type().String())
+		hashValue(h, e)
+	default:
+		panic("ponu cache: hash of uncomparable type " + v.Type().String())
+	}
+}
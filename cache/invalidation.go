@@ -0,0 +1,320 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// 删除后保留版本号的默认时间，这段时间内延迟到达的旧版本设置被丢弃
+const defaultTombstoneTTL = time.Minute
+
+// Versioned 带版本号的value，版本号由业务提供(比如数据库中记录的版本)，0表示没有版本
+type Versioned[V any] struct {
+	Value   V
+	Version uint64
+}
+
+// VersionedEntry 带版本号的设置
+type VersionedEntry[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Version uint64
+}
+
+// Invalidation 其他进程发布的失效消息
+type Invalidation[K comparable, V any] struct {
+	Deletes []K
+	Sets    []VersionedEntry[K, V]
+}
+
+// InvalidationBus 进程间广播缓存失效的总线，发布的消息只投递给其他进程的订阅者
+type InvalidationBus[K comparable, V any] interface {
+	// Publish 广播删除keys
+	Publish(keys []K) error
+	// PublishSet 广播带版本号的设置
+	PublishSet(entries []VersionedEntry[K, V]) error
+	// Subscribe 订阅其他进程发布的消息，回调可能在任意协程中执行，返回取消订阅的函数
+	Subscribe(fun func(Invalidation[K, V])) func()
+}
+
+// subscribers 总线实现共用的订阅者列表
+type subscribers[K comparable, V any] struct {
+	locker sync.RWMutex
+	nextId int
+	funs   map[int]func(Invalidation[K, V])
+}
+
+func (s *subscribers[K, V]) subscribe(fun func(Invalidation[K, V])) func() {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if s.funs == nil {
+		s.funs = make(map[int]func(Invalidation[K, V]))
+	}
+	s.nextId++
+	id := s.nextId
+	s.funs[id] = fun
+	return func() {
+		s.locker.Lock()
+		defer s.locker.Unlock()
+		delete(s.funs, id)
+	}
+}
+
+func (s *subscribers[K, V]) deliver(inv Invalidation[K, V]) {
+	s.locker.RLock()
+	funs := make([]func(Invalidation[K, V]), 0, len(s.funs))
+	for _, fun := range s.funs {
+		funs = append(funs, fun)
+	}
+	s.locker.RUnlock()
+	for _, fun := range funs {
+		fun(inv)
+	}
+}
+
+// LoopbackHub 进程内的总线，用于测试或者同一进程中的多个缓存，每个NewBus相当于一个进程
+type LoopbackHub[K comparable, V any] struct {
+	locker sync.RWMutex
+	buses  []*LoopbackBus[K, V]
+}
+
+// NewLoopbackHub ...
+func NewLoopbackHub[K comparable, V any]() *LoopbackHub[K, V] {
+	return &LoopbackHub[K, V]{}
+}
+
+// NewBus 创建连接到hub的总线
+func (h *LoopbackHub[K, V]) NewBus() *LoopbackBus[K, V] {
+	b := &LoopbackBus[K, V]{hub: h}
+	h.locker.Lock()
+	h.buses = append(h.buses, b)
+	h.locker.Unlock()
+	return b
+}
+
+func (h *LoopbackHub[K, V]) broadcast(from *LoopbackBus[K, V], inv Invalidation[K, V]) {
+	h.locker.RLock()
+	buses := append([]*LoopbackBus[K, V](nil), h.buses...)
+	h.locker.RUnlock()
+	for _, b := range buses {
+		if b != from {
+			b.subs.deliver(inv)
+		}
+	}
+}
+
+// LoopbackBus 在发布的协程中同步投递给hub中的其他总线
+type LoopbackBus[K comparable, V any] struct {
+	hub  *LoopbackHub[K, V]
+	subs subscribers[K, V]
+}
+
+func (b *LoopbackBus[K, V]) Publish(keys []K) error {
+	b.hub.broadcast(b, Invalidation[K, V]{Deletes: keys})
+	return nil
+}
+
+func (b *LoopbackBus[K, V]) PublishSet(entries []VersionedEntry[K, V]) error {
+	b.hub.broadcast(b, Invalidation[K, V]{Sets: entries})
+	return nil
+}
+
+func (b *LoopbackBus[K, V]) Subscribe(fun func(Invalidation[K, V])) func() {
+	return b.subs.subscribe(fun)
+}
+
+type tombstone struct {
+	version    uint64
+	expireTime time.Time
+}
+
+type tombstoneKey[K comparable] struct {
+	key        K
+	expireTime time.Time
+}
+
+// Synced 通过总线和其他进程同步的缓存，c必须是线程安全的
+// 本地的Delete和SetVersion会广播，其他进程的删除直接应用，设置只在版本号比本地的新时应用
+// Set用于从数据库读取后填充缓存，不广播，版本号为0
+// 删除时保留本地的版本号一段时间(墓碑)，删除之后才到达的旧版本设置被丢弃；
+// 删除时本地没有带版本号的值则无法判断，这种情况下仍然要求发布者不乱序
+type Synced[K comparable, V any] struct {
+	c            Cache[K, Versioned[V]]
+	bus          InvalidationBus[K, V]
+	locker       sync.Mutex // 保证版本比较和设置是原子的
+	tombstoneTTL time.Duration
+	tombstones   map[K]tombstone
+	expireQueue  []tombstoneKey[K] // 按过期时间排序，用于回收墓碑
+	unsubscribe  func()
+	onErrorFun   func(error)
+}
+
+// NewSynced 订阅bus，不再使用时调用Close取消订阅
+func NewSynced[K comparable, V any](c Cache[K, Versioned[V]], bus InvalidationBus[K, V]) *Synced[K, V] {
+	if c == nil || bus == nil {
+		panic("ponu cache: Synced need cache and invalidation bus")
+	}
+	s := &Synced[K, V]{
+		c:            c,
+		bus:          bus,
+		tombstoneTTL: defaultTombstoneTTL,
+		tombstones:   make(map[K]tombstone),
+	}
+	s.unsubscribe = bus.Subscribe(s.apply)
+	return s
+}
+
+// WithOnPublishError 广播失败时回调
+func (s *Synced[K, V]) WithOnPublishError(fun func(error)) {
+	s.onErrorFun = fun
+}
+
+// WithTombstoneTTL 设置删除后保留版本号的时间，需要大于消息可能延迟的时间，默认1分钟
+func (s *Synced[K, V]) WithTombstoneTTL(ttl time.Duration) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.tombstoneTTL = ttl
+}
+
+// Close 取消订阅
+func (s *Synced[K, V]) Close() {
+	s.unsubscribe()
+}
+
+func (s *Synced[K, V]) apply(inv Invalidation[K, V]) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	for _, k := range inv.Deletes {
+		s.delete(k)
+	}
+	for _, e := range inv.Sets {
+		s.setIfNewer(e.Key, e.Value, e.Version)
+	}
+}
+
+func (s *Synced[K, V]) setIfNewer(key K, value V, version uint64) bool {
+	if old, o := s.c.Get(key); o && old.Version >= version {
+		return false
+	}
+	s.purgeTombstones(time.Now())
+	if t, o := s.tombstones[key]; o && t.version >= version {
+		return false
+	}
+	s.c.Set(key, Versioned[V]{Value: value, Version: version})
+	return true
+}
+
+// delete 删除本地，有版本号时留下墓碑，需要持有锁
+func (s *Synced[K, V]) delete(key K) bool {
+	old, o := s.c.Get(key)
+	if !o {
+		return false
+	}
+	s.c.Delete(key)
+	if old.Version == 0 {
+		return true
+	}
+	now := time.Now()
+	s.purgeTombstones(now)
+	if t, o := s.tombstones[key]; o && t.version > old.Version {
+		old.Version = t.version
+	}
+	expireTime := now.Add(s.tombstoneTTL)
+	s.tombstones[key] = tombstone{version: old.Version, expireTime: expireTime}
+	s.expireQueue = append(s.expireQueue, tombstoneKey[K]{key: key, expireTime: expireTime})
+	return true
+}
+
+// purgeTombstones 回收过期的墓碑，需要持有锁
+func (s *Synced[K, V]) purgeTombstones(now time.Time) {
+	var i int
+	for ; i < len(s.expireQueue) && !s.expireQueue[i].expireTime.After(now); i++ {
+		k := s.expireQueue[i].key
+		if t, o := s.tombstones[k]; o && !t.expireTime.After(now) {
+			delete(s.tombstones, k)
+		}
+	}
+	if i > 0 {
+		s.expireQueue = append(s.expireQueue[:0], s.expireQueue[i:]...)
+	}
+}
+
+func (s *Synced[K, V]) publishErr(err error) {
+	if err != nil && s.onErrorFun != nil {
+		s.onErrorFun(err)
+	}
+}
+
+func (s *Synced[K, V]) Get(key K) (V, bool) {
+	v, o := s.c.Get(key)
+	return v.Value, o
+}
+
+// GetVersioned 获取value和版本号
+func (s *Synced[K, V]) GetVersioned(key K) (Versioned[V], bool) {
+	return s.c.Get(key)
+}
+
+// Set 只设置本地，不覆盖有版本号的value
+func (s *Synced[K, V]) Set(key K, value V) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if old, o := s.c.Get(key); o && old.Version > 0 {
+		return
+	}
+	s.c.Set(key, Versioned[V]{Value: value})
+}
+
+// SetVersion 版本号比本地的新时设置并广播，返回是否设置了本地
+func (s *Synced[K, V]) SetVersion(key K, value V, version uint64) bool {
+	s.locker.Lock()
+	o := s.setIfNewer(key, value, version)
+	s.locker.Unlock()
+	// 本地拒绝的旧版本不再广播，其他进程也会拒绝
+	if o {
+		s.publishErr(s.bus.PublishSet([]VersionedEntry[K, V]{{Key: key, Value: value, Version: version}}))
+	}
+	return o
+}
+
+// Delete 删除本地并广播
+func (s *Synced[K, V]) Delete(key K) bool {
+	s.locker.Lock()
+	o := s.delete(key)
+	s.locker.Unlock()
+	s.publishErr(s.bus.Publish([]K{key}))
+	return o
+}
+
+// Invalidate 批量删除本地并广播
+func (s *Synced[K, V]) Invalidate(keys ...K) {
+	s.locker.Lock()
+	for _, k := range keys {
+		s.delete(k)
+	}
+	s.locker.Unlock()
+	s.publishErr(s.bus.Publish(keys))
+}
+
+func (s *Synced[K, V]) Has(key K) bool {
+	return s.c.Has(key)
+}
+
+func (s *Synced[K, V]) Len() int32 {
+	return s.c.Len()
+}
+
+// Clear 只清空本地，墓碑保留
+func (s *Synced[K, V]) Clear() {
+	s.c.Clear()
+}
+
+func (s *Synced[K, V]) Range(f func(key K, value V) bool) {
+	s.c.Range(func(k K, v Versioned[V]) bool {
+		return f(k, v.Value)
+	})
+}
+
+func (s *Synced[K, V]) Keys() []K {
+	return s.c.Keys()
+}
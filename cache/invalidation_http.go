@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/huoshan017/ponu/codec"
+	phttp "github.com/huoshan017/ponu/http"
+)
+
+// 发布的默认超时时间，发布是同步的，避免一个没有响应的peer一直阻塞调用者
+const defaultPublishTimeout = 3 * time.Second
+
+// 接收消息的默认最大字节数，超过时返回413
+const defaultMaxBodySize = 4 << 20
+
+type httpInvalidationSet struct {
+	Key     []byte `json:"key"`
+	Value   []byte `json:"value"`
+	Version uint64 `json:"version"`
+}
+
+type httpInvalidation struct {
+	Deletes [][]byte              `json:"deletes,omitempty"`
+	Sets    []httpInvalidationSet `json:"sets,omitempty"`
+}
+
+// HTTPBus 通过http广播的总线，发布时向每个peer的url发送POST请求，
+// 接收用Register注册到ponu的http.Service上，或者直接作为http.Handler
+type HTTPBus[K comparable, V any] struct {
+	peers      []string
+	keyCodec   codec.Codec[K]
+	valueCodec codec.Codec[V]
+	client     *http.Client
+	maxBody    int64
+	subs       subscribers[K, V]
+}
+
+// NewHTTPBus peers是其他进程接收失效消息的url
+func NewHTTPBus[K comparable, V any](peers []string, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) *HTTPBus[K, V] {
+	return &HTTPBus[K, V]{
+		peers:      peers,
+		keyCodec:   keyCodec,
+		valueCodec: valueCodec,
+		client:     &http.Client{Timeout: defaultPublishTimeout},
+		maxBody:    defaultMaxBodySize,
+	}
+}
+
+// WithTimeout 设置发布给每个peer的超时时间，默认3秒
+func (b *HTTPBus[K, V]) WithTimeout(t time.Duration) {
+	b.client.Timeout = t
+}
+
+// WithMaxBodySize 设置接收消息的最大字节数，默认4MB
+func (b *HTTPBus[K, V]) WithMaxBodySize(n int64) {
+	b.maxBody = n
+}
+
+// Register 在service上注册接收失效消息的pattern
+func (b *HTTPBus[K, V]) Register(service *phttp.Service, pattern string) {
+	service.Handle(pattern, b)
+}
+
+func (b *HTTPBus[K, V]) Publish(keys []K) error {
+	var msg httpInvalidation
+	for _, k := range keys {
+		data, err := b.keyCodec.Marshal(k)
+		if err != nil {
+			return err
+		}
+		msg.Deletes = append(msg.Deletes, data)
+	}
+	return b.post(&msg)
+}
+
+func (b *HTTPBus[K, V]) PublishSet(entries []VersionedEntry[K, V]) error {
+	var msg httpInvalidation
+	for _, e := range entries {
+		k, err := b.keyCodec.Marshal(e.Key)
+		if err != nil {
+			return err
+		}
+		v, err := b.valueCodec.Marshal(e.Value)
+		if err != nil {
+			return err
+		}
+		msg.Sets = append(msg.Sets, httpInvalidationSet{Key: k, Value: v, Version: e.Version})
+	}
+	return b.post(&msg)
+}
+
+// post 发送给所有peer，返回所有失败的peer的错误，peer返回的状态码不是2xx也算失败
+func (b *HTTPBus[K, V]) post(msg *httpInvalidation) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, peer := range b.peers {
+		if err := b.postPeer(peer, data); err != nil {
+			errs = append(errs, fmt.Errorf("ponu cache: publish invalidation to %v: %w", peer, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *HTTPBus[K, V]) postPeer(peer string, data []byte) error {
+	resp, err := b.client.Post(peer, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %v", resp.Status)
+	}
+	return nil
+}
+
+func (b *HTTPBus[K, V]) Subscribe(fun func(Invalidation[K, V])) func() {
+	return b.subs.subscribe(fun)
+}
+
+// ServeHTTP 接收其他进程发布的消息并投递给订阅者
+func (b *HTTPBus[K, V]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, b.maxBody))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		return
+	}
+	var msg httpInvalidation
+	if err = json.Unmarshal(data, &msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var inv Invalidation[K, V]
+	for _, d := range msg.Deletes {
+		k, err := b.keyCodec.Unmarshal(d)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		inv.Deletes = append(inv.Deletes, k)
+	}
+	for _, s := range msg.Sets {
+		e := VersionedEntry[K, V]{Version: s.Version}
+		if e.Key, err = b.keyCodec.Unmarshal(s.Key); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if e.Value, err = b.valueCodec.Unmarshal(s.Value); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		inv.Sets = append(inv.Sets, e)
+	}
+	b.subs.deliver(inv)
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huoshan017/ponu/codec"
+	phttp "github.com/huoshan017/ponu/http"
+)
+
+var (
+	_ Cache[int32, string]           = (*Synced[int32, string])(nil)
+	_ InvalidationBus[int32, string] = (*LoopbackBus[int32, string])(nil)
+	_ InvalidationBus[int32, string] = (*HTTPBus[int32, string])(nil)
+)
+
+func checkSynced(t *testing.T, name string, s *Synced[int32, string], key int32, value string, version uint64) {
+	v, o := s.GetVersioned(key)
+	if value == "" {
+		if o {
+			t.Fatalf("%v key %v must be deleted, got %v", name, key, v)
+		}
+		return
+	}
+	if !o || v.Value != value || v.Version != version {
+		t.Fatalf("%v key %v got %+v %v, expect %v %v", name, key, v, o, value, version)
+	}
+}
+
+func TestSyncedLoopback(t *testing.T) {
+	hub := NewLoopbackHub[int32, string]()
+	a := NewSynced[int32, string](NewConcurrentLFU[int32, Versioned[string]](100), hub.NewBus())
+	b := NewSynced[int32, string](NewLRUWithLock[int32, Versioned[string]](100), hub.NewBus())
+	c := NewSynced[int32, string](NewSharded(4, func() Cache[int32, Versioned[string]] { return NewARC[int32, Versioned[string]](100) }), hub.NewBus())
+
+	for _, s := range []*Synced[int32, string]{a, b, c} {
+		s.Set(1, "fill")
+	}
+	a.Delete(1)
+	for i, s := range []*Synced[int32, string]{a, b, c} {
+		checkSynced(t, fmt.Sprint("delete ", i), s, 1, "", 0)
+	}
+
+	b.Set(2, "fill")
+	if !a.SetVersion(2, "v2", 2) {
+		t.Fatalf("set version failed")
+	}
+	for i, s := range []*Synced[int32, string]{a, b, c} {
+		checkSynced(t, fmt.Sprint("set version ", i), s, 2, "v2", 2)
+	}
+	// 旧版本的设置和本地填充都不覆盖新版本，本地拒绝的设置不广播
+	var sets int
+	hub.NewBus().Subscribe(func(inv Invalidation[int32, string]) { sets += len(inv.Sets) })
+	if c.SetVersion(2, "v1", 1) {
+		t.Fatalf("set older version must fail")
+	}
+	if sets != 0 {
+		t.Fatalf("rejected set version published")
+	}
+	b.Set(2, "fill")
+	for i, s := range []*Synced[int32, string]{a, b, c} {
+		checkSynced(t, fmt.Sprint("older version ", i), s, 2, "v2", 2)
+	}
+
+	c.Close()
+	a.Invalidate(2)
+	checkSynced(t, "invalidate", b, 2, "", 0)
+	checkSynced(t, "closed", c, 2, "v2", 2)
+}
+
+func TestSyncedTombstone(t *testing.T) {
+	hub := NewLoopbackHub[int32, string]()
+	a := NewSynced[int32, string](NewLRUWithLock[int32, Versioned[string]](100), hub.NewBus())
+	b := NewSynced[int32, string](NewLRUWithLock[int32, Versioned[string]](100), hub.NewBus())
+	b.WithTombstoneTTL(50 * time.Millisecond)
+	delayed := Invalidation[int32, string]{Sets: []VersionedEntry[int32, string]{{Key: 1, Value: "v1", Version: 1}}}
+
+	a.SetVersion(1, "v1", 1)
+	a.Delete(1)
+	// 删除之后才到达的旧版本设置被丢弃
+	b.apply(delayed)
+	checkSynced(t, "delayed set", b, 1, "", 0)
+	a.SetVersion(1, "v2", 2)
+	checkSynced(t, "newer set", b, 1, "v2", 2)
+
+	// 墓碑过期之后不再比较
+	b.Delete(1)
+	b.apply(delayed)
+	checkSynced(t, "delayed set after local delete", b, 1, "", 0)
+	time.Sleep(60 * time.Millisecond)
+	b.apply(delayed)
+	checkSynced(t, "tombstone expired", b, 1, "v1", 1)
+	if len(b.tombstones) != 0 || len(b.expireQueue) != 0 {
+		t.Fatalf("tombstones %v queue %v not purged", len(b.tombstones), len(b.expireQueue))
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestSyncedHTTP(t *testing.T) {
+	var (
+		kc           = codec.Binary[int32]{}
+		vc           = codec.String{}
+		addrA, addrB = freeAddr(t), freeAddr(t)
+		busA         = NewHTTPBus[int32, string]([]string{"http://" + addrB + "/cache/invalidate"}, kc, vc)
+		busB         = NewHTTPBus[int32, string]([]string{"http://" + addrA + "/cache/invalidate"}, kc, vc)
+		serviceA     phttp.Service
+		serviceB     phttp.Service
+	)
+	serviceA.Init()
+	busA.Register(&serviceA, "/cache/invalidate")
+	serviceA.GoRun(addrA)
+	serviceB.Init()
+	busB.Register(&serviceB, "/cache/invalidate")
+	serviceB.GoRun(addrB)
+	for _, addr := range []string{addrA, addrB} {
+		for i := 0; ; i++ {
+			if conn, err := net.Dial("tcp", addr); err == nil {
+				conn.Close()
+				break
+			} else if i > 100 {
+				t.Fatalf("service %v not started: %v", addr, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	a := NewSynced[int32, string](NewLRUWithLock[int32, Versioned[string]](100), busA)
+	b := NewSynced[int32, string](NewLRUWithLock[int32, Versioned[string]](100), busB)
+	a.Set(1, "fill")
+	b.Set(1, "fill")
+	a.Delete(1)
+	checkSynced(t, "http delete", b, 1, "", 0)
+	b.SetVersion(3, "v3", 3)
+	checkSynced(t, "http set version", a, 3, "v3", 3)
+	a.SetVersion(3, "v2", 2)
+	checkSynced(t, "http older version", b, 3, "v3", 3)
+
+	var errNum int
+	down := NewSynced[int32, string](NewLRUWithLock[int32, Versioned[string]](100),
+		NewHTTPBus[int32, string]([]string{"http://" + freeAddr(t) + "/cache/invalidate"}, kc, vc))
+	down.WithOnPublishError(func(err error) { errNum++ })
+	down.Delete(1)
+	if errNum != 1 {
+		t.Fatalf("publish to unreachable peer must fail")
+	}
+
+	// peer返回的状态码不是2xx算失败
+	wrongPath := NewSynced[int32, string](NewLRUWithLock[int32, Versioned[string]](100),
+		NewHTTPBus[int32, string]([]string{"http://" + addrB + "/cache/wrong"}, kc, vc))
+	wrongPath.WithOnPublishError(func(err error) { errNum++ })
+	wrongPath.Delete(1)
+	if errNum != 2 {
+		t.Fatalf("publish to wrong path must fail")
+	}
+	wrongCodec := NewSynced[int32, string](NewLRUWithLock[int32, Versioned[string]](100),
+		NewHTTPBus[int32, string]([]string{"http://" + addrB + "/cache/invalidate"}, codec.JSON[int32]{}, vc))
+	wrongCodec.WithOnPublishError(func(err error) { errNum++ })
+	wrongCodec.Delete(1)
+	if errNum != 3 {
+		t.Fatalf("publish with mismatched codec must fail")
+	}
+
+	// 消息超过最大字节数时返回413
+	busA.WithMaxBodySize(16)
+	w := httptest.NewRecorder()
+	busA.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/cache/invalidate", strings.NewReader(strings.Repeat(" ", 17)+"{}")))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body got status %v", w.Code)
+	}
+
+	// 没有响应的peer在超时后返回
+	stop := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stop
+	}))
+	defer hung.Close()
+	defer close(stop)
+	bus := NewHTTPBus[int32, string]([]string{hung.URL}, kc, vc)
+	bus.WithTimeout(50 * time.Millisecond)
+	start := time.Now()
+	if err := bus.Publish([]int32{1}); err == nil || time.Since(start) > time.Second {
+		t.Fatalf("publish to hung peer got %v after %v", err, time.Since(start))
+	}
+}
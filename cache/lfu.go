@@ -17,20 +17,40 @@ const (
 type node[K comparable, V any] struct {
 	Pair[K, V]
 	f int32
+	w int64 // 权重
 }
 
 type lfuBase[K comparable, V any] struct {
+	statsBase
 	cap         int32                   // 容量
+	weigher     func(K, V) int64        // 权重函数，为nil时每个元素的权重为1，按cap限制
+	maxWeight   int64                   // 设置了weigher时的最大总权重
+	size        int64                   // 本对象中元素的总权重
 	onEvictFun  func(K, V)              // value的回收内存函数
 	expiredTime time.Duration           // 超时淘汰时间
-	ts          *int32                  // 总大小
+	ts          *int64                  // 多个对象共享的总权重
 	l           list.List               // 数据列表
 	k2i         map[K]list.Iterator     // key对应的列表节点
 	f2i         map[int32]list.Iterator // 保存訪問次數對應的迭代器，這個迭代器是該訪問次數下最新的，如果有更新的迭代器，則插在它之後
 	createTime  time.Time               // 创建时间
 	k2t         map[K]time.Duration     // key对应的时间点
+	aging       agingMode               // 频次老化方式
+	agingOps    int32                   // 每多少次操作频次减半
+	agingPeriod time.Duration           // 每隔多长时间频次减半
+	ops         int32                   // 上次减半之后的操作次数
+	agingTime   time.Time               // 上次减半的时间
+	age         int32                   // LFU-DA的缓存年龄，为最近一次被淘汰的元素的频次
 }
 
+type agingMode int8
+
+const (
+	agingModeNone    agingMode = iota
+	agingModeOps               // 每N次操作所有频次减半
+	agingModePeriod            // 每隔一段时间所有频次减半
+	agingModeDynamic           // LFU-DA，新元素的频次从缓存年龄开始
+)
+
 func newLFUBase[K comparable, V any](cap int32) *lfuBase[K, V] {
 	if cap < minCap {
 		cap = minCap
@@ -45,7 +65,7 @@ func newLFUBase[K comparable, V any](cap int32) *lfuBase[K, V] {
 	}
 }
 
-func newLFUBaseWithTotalSize[K comparable, V any](cap int32, totalSize *int32) *lfuBase[K, V] {
+func newLFUBaseWithTotalSize[K comparable, V any](cap int32, totalSize *int64) *lfuBase[K, V] {
 	if cap < minCap {
 		cap = minCap
 	}
@@ -64,6 +84,106 @@ func (lfu *lfuBase[K, V]) WithOnEvict(fun func(K, V)) {
 	lfu.onEvictFun = fun
 }
 
+// WithWeigher 按权重限制容量，总权重超过maxWeight时淘汰访问频次最低的元素，需要在使用前设置
+func (lfu *lfuBase[K, V]) WithWeigher(weigher func(K, V) int64, maxWeight int64) {
+	lfu.weigher = weigher
+	lfu.maxWeight = maxWeight
+}
+
+// GetWeight 总权重，共享总权重时是所有对象的
+func (lfu *lfuBase[K, V]) GetWeight() int64 {
+	if lfu.ts != nil {
+		return atomic.LoadInt64(lfu.ts)
+	}
+	return lfu.size
+}
+
+func (lfu *lfuBase[K, V]) weigh(key K, value V) int64 {
+	if lfu.weigher == nil {
+		return 1
+	}
+	return lfu.weigher(key, value)
+}
+
+func (lfu *lfuBase[K, V]) limit() int64 {
+	if lfu.weigher == nil {
+		return int64(lfu.cap)
+	}
+	return lfu.maxWeight
+}
+
+// addSize 增加权重，返回增加后的总权重
+func (lfu *lfuBase[K, V]) addSize(w int64) int64 {
+	lfu.size += w
+	if lfu.ts != nil {
+		return atomic.AddInt64(lfu.ts, w)
+	}
+	return lfu.size
+}
+
+// evictOverweight 总权重total超出时淘汰访问频次最低的元素，不淘汰key
+// 共享总权重时只淘汰本对象中的元素，本对象已空则允许暂时超出，由其他对象在Set时淘汰
+// 對於一個Shard來説，同一時間保證只有一個goroutine對其Set操作
+func (lfu *lfuBase[K, V]) evictOverweight(total int64, key K) {
+	for total > lfu.limit() {
+		front := lfu.l.Front()
+		if front == nil || front.(node[K, V]).k == key || !lfu.deleteFirst() {
+			return
+		}
+		total = lfu.GetWeight()
+	}
+}
+
+// WithHalvingEveryOps 每n次Set和Get操作后所有元素的访问频次减半
+func (lfu *lfuBase[K, V]) WithHalvingEveryOps(n int32) {
+	lfu.aging = agingModeOps
+	lfu.agingOps = n
+	lfu.ops = 0
+}
+
+// WithHalvingPeriod 每隔d时间所有元素的访问频次减半，在Set和Get时检查
+func (lfu *lfuBase[K, V]) WithHalvingPeriod(d time.Duration) {
+	lfu.aging = agingModePeriod
+	lfu.agingPeriod = d
+	lfu.agingTime = time.Now()
+}
+
+// WithDynamicAging 使用LFU-DA，新元素的初始频次为最近一次被淘汰的元素的频次加一，
+// 这样长期不访问的旧热点最终会被新元素超过
+func (lfu *lfuBase[K, V]) WithDynamicAging() {
+	lfu.aging = agingModeDynamic
+}
+
+// tick Set和Get时调用，到了减半的时机则所有频次减半
+func (lfu *lfuBase[K, V]) tick() {
+	switch lfu.aging {
+	case agingModeOps:
+		lfu.ops++
+		if lfu.ops >= lfu.agingOps {
+			lfu.ops = 0
+			lfu.halve()
+		}
+	case agingModePeriod:
+		if now := time.Now(); now.Sub(lfu.agingTime) >= lfu.agingPeriod {
+			lfu.agingTime = now
+			lfu.halve()
+		}
+	}
+}
+
+// halve 所有频次f变为(f+1)/2，变换是单调的，链表的顺序不变，只需重建f2i
+func (lfu *lfuBase[K, V]) halve() {
+	for f := range lfu.f2i {
+		delete(lfu.f2i, f)
+	}
+	for iter := lfu.l.Begin(); iter != lfu.l.End(); iter = iter.Next() {
+		n := iter.Value().(node[K, V])
+		n.f = (n.f + 1) / 2
+		lfu.l.Update(n, iter)
+		lfu.f2i[n.f] = iter
+	}
+}
+
 func (lfu *lfuBase[K, V]) WithExpiredtime(t time.Duration) {
 	if t < minExpiredTime {
 		t = minExpiredTime
@@ -81,40 +201,21 @@ func (lfu *lfuBase[K, V]) Set(key K, value V) {
 	if lfu.k2t == nil {
 		lfu.k2t = make(map[K]time.Duration)
 	}
+	lfu.tick()
+	w := lfu.weigh(key, value)
+	if w > lfu.limit() { // 单个元素超出容量，不缓存
+		lfu.delete(key)
+		return
+	}
 	iter, o := lfu.k2i[key]
 	if !o { // 插入
-		if lfu.ts == nil {
-			if lfu.cap <= int32(len(lfu.k2i)) { // 超出容量大小
-				lfu.deleteFirst()
-			}
-			lfu.add(key, value)
-		} else {
-			if lfu.cap <= atomic.LoadInt32(lfu.ts) { // 原子操作判斷大小已達最大容量
-				// 刪除掉一個最不常訪問的，再添加一個，保持總數量不變
-				// 如果刪除失敗，表示鏈表已空，總數量加一。
-				// 對於一個Shard來説，同一時間保證只有一個goroutine對其Set操作
-				if !lfu.deleteFirst() {
-					atomic.AddInt32(lfu.ts, 1)
-				}
-				lfu.add(key, value)
-			} else {
-				// 前面通過原子操作知道totalSize沒到最大容量cap
-				// 再進行原子操作加一后得到當前大小，這時有兩種結果：
-				// 1. 大於容量cap，表示這中間有其他goroutine添加了元素，則把當前大小減一(盡可能快，
-				//	  因爲會影響到其他goroutine)，刪掉一個再添加一個，保持總數量不變
-				// 2. 小於等於cap，則直接添加元素
-				if lfu.cap < atomic.AddInt32(lfu.ts, 1) {
-					if lfu.deleteFirst() {
-						atomic.AddInt32(lfu.ts, -1)
-					}
-					lfu.add(key, value)
-				} else {
-					lfu.add(key, value)
-				}
-			}
-		}
+		// 先加上新元素的权重，超出容量则先淘汰，再添加
+		lfu.evictOverweight(lfu.addSize(w), key)
+		lfu.add(key, value, w)
 	} else { // 更新
-		lfu.updateValue(iter, true, value)
+		old := iter.Value().(node[K, V]).w
+		lfu.updateValue(iter, true, value, w)
+		lfu.evictOverweight(lfu.addSize(w-old), key)
 	}
 	if lfu.expiredTime > 0 {
 		lfu.k2t[key] = lfu.getExpiredTimePoint(time.Now(), lfu.expiredTime)
@@ -123,25 +224,33 @@ func (lfu *lfuBase[K, V]) Set(key K, value V) {
 
 func (lfu *lfuBase[K, V]) SetExpired(key K, value V, expiredTime time.Duration) {
 	lfu.Set(key, value)
-	lfu.k2t[key] = lfu.getExpiredTimePoint(time.Now(), expiredTime)
+	if _, o := lfu.k2i[key]; o {
+		lfu.k2t[key] = lfu.getExpiredTimePoint(time.Now(), expiredTime)
+	}
 }
 
 func (lfu *lfuBase[K, V]) Get(key K) (V, bool) {
 	var v V
 	if lfu.k2i == nil {
+		lfu.recordGet(false)
 		return v, false
 	}
+	lfu.tick()
 	iter, o := lfu.k2i[key]
 	if !o {
+		lfu.recordGet(false)
 		return v, false
 	}
 	if lfu.isKeyExpired(key) {
 		lfu.delete(key)
+		lfu.recordEviction()
+		lfu.recordGet(false)
 		return v, false
 	}
 	// iter在update之後有可能會失效，所以要在update之前取到其中的值
 	v = iter.Value().(node[K, V]).v
 	lfu.update(iter)
+	lfu.recordGet(true)
 	return v, true
 }
 
@@ -149,11 +258,17 @@ func (lfu *lfuBase[K, V]) Delete(key K) bool {
 	return lfu.delete(key)
 }
 
+// Len 元素个数，包括已过期但还没被删除的
+func (lfu *lfuBase[K, V]) Len() int32 {
+	return int32(len(lfu.k2i))
+}
+
 func (lfu *lfuBase[K, V]) Has(key K) bool {
 	_, o := lfu.k2i[key]
 	if o {
 		if lfu.isKeyExpired(key) {
 			lfu.delete(key)
+			lfu.recordEviction()
 			return false
 		}
 	}
@@ -166,6 +281,7 @@ func (lfu *lfuBase[K, V]) ToList(lis *list.ListT[Pair[K, V]]) {
 		if lfu.isKeyExpired(n.k) {
 			iter = iter.Next()
 			lfu.delete(n.k)
+			lfu.recordEviction()
 			continue
 		}
 		lis.PushBack(Pair[K, V]{k: n.k, v: n.v})
@@ -173,22 +289,38 @@ func (lfu *lfuBase[K, V]) ToList(lis *list.ListT[Pair[K, V]]) {
 	}
 }
 
+// Range 从访问频次最低的元素开始遍历
+func (lfu *lfuBase[K, V]) Range(f func(key K, value V) bool) {
+	for iter := lfu.l.Begin(); iter != lfu.l.End(); iter = iter.Next() {
+		n := iter.Value().(node[K, V])
+		if lfu.isKeyExpired(n.k) {
+			continue
+		}
+		if !f(n.k, n.v) {
+			return
+		}
+	}
+}
+
+func (lfu *lfuBase[K, V]) Keys() []K {
+	return collectKeys(lfu.Len(), lfu.Range)
+}
+
 func (lfu *lfuBase[K, V]) Clear() {
 	lfu.l.Clear()
 	lfu.k2i = nil
 	lfu.f2i = nil
 	lfu.k2t = nil
-	if lfu.ts != nil {
-		atomic.AddInt32(lfu.ts, -lfu.l.GetLength())
-	}
+	lfu.addSize(-lfu.size)
+	lfu.age = 0
 }
 
 func (lfu *lfuBase[K, V]) update(iter list.Iterator) {
 	var v V
-	lfu.updateValue(iter, false, v)
+	lfu.updateValue(iter, false, v, 0)
 }
 
-func (lfu *lfuBase[K, V]) updateValue(iter list.Iterator, update bool, val V) {
+func (lfu *lfuBase[K, V]) updateValue(iter list.Iterator, update bool, val V, w int64) {
 	var (
 		n             node[K, V]
 		f             int32
@@ -208,6 +340,7 @@ func (lfu *lfuBase[K, V]) updateValue(iter list.Iterator, update bool, val V) {
 	n.f += 1
 	if update {
 		n.v = val
+		n.w = w
 	}
 
 	// 獲取f+1次最新訪問的元素迭代器
@@ -266,6 +399,11 @@ func (lfu *lfuBase[K, V]) deleteFirst() bool {
 	delete(lfu.k2i, n.k)
 	lfu.l.PopFront()
 	delete(lfu.k2t, n.k)
+	lfu.addSize(-n.w)
+	lfu.recordEviction()
+	if lfu.aging == agingModeDynamic && n.f > lfu.age {
+		lfu.age = n.f
+	}
 	if lfu.onEvictFun != nil {
 		lfu.onEvictFun(n.k, n.v)
 	}
@@ -284,25 +422,31 @@ func (lfu *lfuBase[K, V]) delete(key K) bool {
 	delete(lfu.k2i, key)
 	lfu.l.Delete(iter)
 	delete(lfu.k2t, n.k)
+	lfu.addSize(-n.w)
 	if lfu.onEvictFun != nil {
 		lfu.onEvictFun(n.k, n.v)
 	}
-	if lfu.ts != nil {
-		atomic.AddInt32(lfu.ts, -1)
-	}
 	return true
 }
 
-func (lfu *lfuBase[K, V]) add(key K, value V) {
-	var iter list.Iterator
-	niter, o := lfu.f2i[1]
-	if !o { // 沒有訪問次數為1對應的迭代器，則新插入的元素肯定為訪問頻次最低的元素，放在鏈表頭
-		lfu.l.PushFront(node[K, V]{Pair: Pair[K, V]{k: key, v: value}, f: 1})
+func (lfu *lfuBase[K, V]) add(key K, value V, w int64) {
+	var (
+		iter list.Iterator
+		f    = lfu.age + 1 // 沒有使用LFU-DA時age為0
+		n    = node[K, V]{Pair: Pair[K, V]{k: key, v: value}, f: f, w: w}
+	)
+	niter, o := lfu.f2i[f]
+	if !o {
+		// LFU-DA中所有元素的頻次都不小於age，頻次為age的元素排在新元素之前
+		niter, o = lfu.f2i[f-1]
+	}
+	if !o { // 沒有訪問次數不大於f的元素，則新插入的元素肯定為訪問頻次最低的元素，放在鏈表頭
+		lfu.l.PushFront(n)
 		iter = lfu.l.Begin()
 	} else { // 把該元素插入到同訪問頻次下最近被訪問的元素之後，然後該新插入元素的迭代器為同頻次最近訪問的
-		iter = lfu.l.InsertContinue(node[K, V]{Pair: Pair[K, V]{k: key, v: value}, f: 1}, niter)
+		iter = lfu.l.InsertContinue(n, niter)
 	}
-	lfu.f2i[1] = iter
+	lfu.f2i[f] = iter
 	lfu.k2i[key] = iter
 	if lfu.expiredTime > 0 {
 		lfu.k2t[key] = lfu.getExpiredTimePoint(time.Now(), lfu.expiredTime)
@@ -347,7 +491,7 @@ func NewLFUWithLock[K comparable, V any](cap int32) *LFUWithLock[K, V] {
 	}
 }
 
-func newLFUWithLockAndTotalSize[K comparable, V any](cap int32, totalSize *int32) *LFUWithLock[K, V] {
+func newLFUWithLockAndTotalSize[K comparable, V any](cap int32, totalSize *int64) *LFUWithLock[K, V] {
 	return &LFUWithLock[K, V]{
 		lfuBase: newLFUBaseWithTotalSize[K, V](cap, totalSize),
 	}
@@ -359,6 +503,36 @@ func (lfu *LFUWithLock[K, V]) WithOnEvict(fun func(K, V)) {
 	lfu.lfuBase.WithOnEvict(fun)
 }
 
+func (lfu *LFUWithLock[K, V]) WithWeigher(weigher func(K, V) int64, maxWeight int64) {
+	lfu.rwlock.Lock()
+	defer lfu.rwlock.Unlock()
+	lfu.lfuBase.WithWeigher(weigher, maxWeight)
+}
+
+func (lfu *LFUWithLock[K, V]) GetWeight() int64 {
+	lfu.rwlock.RLock()
+	defer lfu.rwlock.RUnlock()
+	return lfu.lfuBase.GetWeight()
+}
+
+func (lfu *LFUWithLock[K, V]) WithHalvingEveryOps(n int32) {
+	lfu.rwlock.Lock()
+	defer lfu.rwlock.Unlock()
+	lfu.lfuBase.WithHalvingEveryOps(n)
+}
+
+func (lfu *LFUWithLock[K, V]) WithHalvingPeriod(d time.Duration) {
+	lfu.rwlock.Lock()
+	defer lfu.rwlock.Unlock()
+	lfu.lfuBase.WithHalvingPeriod(d)
+}
+
+func (lfu *LFUWithLock[K, V]) WithDynamicAging() {
+	lfu.rwlock.Lock()
+	defer lfu.rwlock.Unlock()
+	lfu.lfuBase.WithDynamicAging()
+}
+
 func (lfu *LFUWithLock[K, V]) Set(key K, value V) {
 	lfu.rwlock.Lock()
 	defer lfu.rwlock.Unlock()
@@ -371,9 +545,10 @@ func (lfu *LFUWithLock[K, V]) Get(key K) (V, bool) {
 	return lfu.lfuBase.Get(key)
 }
 
+// Has 过期的元素会被删除，所以要加写锁
 func (lfu *LFUWithLock[K, V]) Has(key K) bool {
-	lfu.rwlock.RLock()
-	defer lfu.rwlock.RUnlock()
+	lfu.rwlock.Lock()
+	defer lfu.rwlock.Unlock()
 	return lfu.lfuBase.Has(key)
 }
 
@@ -383,12 +558,32 @@ func (lfu *LFUWithLock[K, V]) Delete(key K) bool {
 	return lfu.lfuBase.Delete(key)
 }
 
-func (lfu *LFUWithLock[K, V]) ToList(lis *list.ListT[Pair[K, V]]) {
+func (lfu *LFUWithLock[K, V]) Len() int32 {
 	lfu.rwlock.RLock()
 	defer lfu.rwlock.RUnlock()
+	return lfu.lfuBase.Len()
+}
+
+// ToList 过期的元素会被删除，所以要加写锁
+func (lfu *LFUWithLock[K, V]) ToList(lis *list.ListT[Pair[K, V]]) {
+	lfu.rwlock.Lock()
+	defer lfu.rwlock.Unlock()
 	lfu.lfuBase.ToList(lis)
 }
 
+// Range 遍历时持有读锁
+func (lfu *LFUWithLock[K, V]) Range(f func(key K, value V) bool) {
+	lfu.rwlock.RLock()
+	defer lfu.rwlock.RUnlock()
+	lfu.lfuBase.Range(f)
+}
+
+func (lfu *LFUWithLock[K, V]) Keys() []K {
+	lfu.rwlock.RLock()
+	defer lfu.rwlock.RUnlock()
+	return lfu.lfuBase.Keys()
+}
+
 func (lfu *LFUWithLock[K, V]) Clear() {
 	lfu.rwlock.Lock()
 	defer lfu.rwlock.Unlock()
@@ -191,3 +191,120 @@ func TestLFUWithLock(t *testing.T) {
 
 	l.Clear()
 }
+
+func TestLFUWeigher(t *testing.T) {
+	var evicted []int
+	l := NewLFU[int, []byte](minCap)
+	l.WithWeigher(func(k int, v []byte) int64 { return int64(len(v)) }, 100)
+	l.WithOnEvict(func(k int, v []byte) { evicted = append(evicted, k) })
+	for i := 1; i <= 4; i++ {
+		l.Set(i, make([]byte, 20))
+		for j := 0; j < i; j++ {
+			l.Get(i)
+		}
+	}
+	// 1的访问频次最低，被淘汰
+	l.Set(5, make([]byte, 30))
+	if l.Has(1) || len(evicted) != 1 || evicted[0] != 1 || l.GetWeight() != 90 {
+		t.Fatalf("evicted %v, weight %v", evicted, l.GetWeight())
+	}
+	l.Set(4, make([]byte, 60))
+	if !l.Has(4) || l.GetWeight() > 100 {
+		t.Fatalf("update weight %v", l.GetWeight())
+	}
+	l.Clear()
+	if l.GetWeight() != 0 {
+		t.Fatalf("weight after clear %v", l.GetWeight())
+	}
+
+	c := NewConcurrentLFU[int32, []byte](minCap)
+	c.WithWeigher(func(k int32, v []byte) int64 { return int64(len(v)) }, 1000)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g)))
+			for n := 0; n < 10000; n++ {
+				c.Set(r.Int31n(1000), make([]byte, r.Intn(50)+1))
+			}
+		}(g)
+	}
+	wg.Wait()
+	var size int64
+	for _, shard := range c.shards {
+		size += shard.size
+	}
+	if size != c.GetWeight() || c.GetWeight() > 1000+50*shardSize {
+		t.Fatalf("concurrent weight %v, sum of shards %v", c.GetWeight(), size)
+	}
+}
+
+// checkLFUOrder 链表按频次非降序排列，f2i指向每个频次的最后一个元素
+func checkLFUOrder(t *testing.T, lfu *lfuBase[int, int]) {
+	last := make(map[int32]list.Iterator)
+	var prev int32
+	for iter := lfu.l.Begin(); iter != lfu.l.End(); iter = iter.Next() {
+		n := iter.Value().(node[int, int])
+		if n.f < prev {
+			t.Fatalf("frequency %v after %v", n.f, prev)
+		}
+		prev = n.f
+		last[n.f] = iter
+	}
+	if len(last) != len(lfu.f2i) {
+		t.Fatalf("f2i length %v, expect %v", len(lfu.f2i), len(last))
+	}
+	for f, iter := range last {
+		if lfu.f2i[f] != iter {
+			t.Fatalf("f2i of frequency %v not the last one", f)
+		}
+	}
+}
+
+func TestLFUAging(t *testing.T) {
+	workload := func(l *LFU[int, int]) int {
+		// 旧的热点
+		for n := 0; n < 100; n++ {
+			for k := 0; k < minCap; k++ {
+				l.Set(k, k)
+			}
+		}
+		// 新的工作集
+		var hit int
+		for n := 0; n < 100; n++ {
+			for k := 100; k < 100+minCap/2; k++ {
+				if _, o := l.Get(k); o {
+					hit++
+				} else {
+					l.Set(k, k)
+				}
+			}
+		}
+		checkLFUOrder(t, l.lfuBase)
+		return hit
+	}
+
+	noAging := workload(NewLFU[int, int](minCap))
+	if noAging != 0 {
+		t.Fatalf("new keys must not be cached without aging, hit %v", noAging)
+	}
+
+	ops := NewLFU[int, int](minCap)
+	ops.WithHalvingEveryOps(50)
+	if hit := workload(ops); hit < 300 {
+		t.Fatalf("halving every ops hit %v", hit)
+	}
+
+	da := NewLFU[int, int](minCap)
+	da.WithDynamicAging()
+	if hit := workload(da); hit < 300 {
+		t.Fatalf("dynamic aging hit %v", hit)
+	}
+
+	period := NewLFU[int, int](minCap)
+	period.WithHalvingPeriod(time.Nanosecond)
+	if hit := workload(period); hit < 300 {
+		t.Fatalf("halving period hit %v", hit)
+	}
+}
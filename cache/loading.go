@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrLoaderPanic loader发生panic时，等待同一次加载的调用者得到包装了它的错误
+var ErrLoaderPanic = errors.New("ponu cache: loader panic")
+
+// Store LoadingCache底层的缓存，需要是线程安全的，
+// LRUWithLock、LFUWithLock、ConcurrentLFU、TinyLFUWithLock都满足
+type Store[K comparable, V any] interface {
+	Get(key K) (V, bool)
+	Set(key K, value V)
+	Delete(key K) bool
+}
+
+// Loaded LoadingCache保存在底层缓存中的值，加载失败时保存错误
+type Loaded[V any] struct {
+	v          V
+	err        error
+	expireTime time.Time // 零值表示不过期
+}
+
+// GetValue ...
+func (l *Loaded[V]) GetValue() V {
+	return l.v
+}
+
+// GetErr ...
+func (l *Loaded[V]) GetErr() error {
+	return l.err
+}
+
+type loadCall[V any] struct {
+	wg          sync.WaitGroup
+	v           V
+	err         error
+	invalidated bool // 加载过程中key被Delete，结果不写入缓存
+}
+
+// LoadingCache 缓存未命中时用loader加载，同一个key并发的加载合并为一次，
+// 加载失败的错误缓存一小段时间，快过期的元素在后台提前刷新
+type LoadingCache[K comparable, V any] struct {
+	statsBase
+	store        Store[K, *Loaded[V]]
+	ttl          time.Duration // 加载成功的值的有效时间，0表示不过期
+	negativeTTL  time.Duration // 加载失败的错误的缓存时间，0表示不缓存错误
+	refreshAhead time.Duration // 剩余有效时间小于refreshAhead时在后台刷新
+	locker       sync.Mutex
+	calls        map[K]*loadCall[V]
+}
+
+// NewLoadingCache ...
+func NewLoadingCache[K comparable, V any](store Store[K, *Loaded[V]]) *LoadingCache[K, V] {
+	return &LoadingCache[K, V]{
+		store: store,
+		calls: make(map[K]*loadCall[V]),
+	}
+}
+
+// WithTTL 加载成功的值的有效时间
+func (c *LoadingCache[K, V]) WithTTL(ttl time.Duration) *LoadingCache[K, V] {
+	c.ttl = ttl
+	return c
+}
+
+// WithNegativeTTL 加载失败的错误的缓存时间
+func (c *LoadingCache[K, V]) WithNegativeTTL(ttl time.Duration) *LoadingCache[K, V] {
+	c.negativeTTL = ttl
+	return c
+}
+
+// WithRefreshAhead 剩余有效时间小于d时，返回旧值并在后台刷新，需要设置了TTL
+func (c *LoadingCache[K, V]) WithRefreshAhead(d time.Duration) *LoadingCache[K, V] {
+	c.refreshAhead = d
+	return c
+}
+
+// GetOrLoad 缓存中有未过期的值或者错误则直接返回，否则用loader加载
+func (c *LoadingCache[K, V]) GetOrLoad(key K, loader func(K) (V, error)) (V, error) {
+	if l, o := c.getLoaded(key); o {
+		if l.err == nil && c.needRefresh(l) && !c.isLoading(key) {
+			go c.load(key, loader, true)
+		}
+		c.recordGet(true)
+		return l.v, l.err
+	}
+	c.recordGet(false)
+	return c.load(key, loader, false)
+}
+
+// Get 只从缓存中获取，不加载，错误也当做不存在
+func (c *LoadingCache[K, V]) Get(key K) (V, bool) {
+	if l, o := c.getLoaded(key); o && l.err == nil {
+		return l.v, true
+	}
+	var v V
+	return v, false
+}
+
+// Set 直接设置值
+func (c *LoadingCache[K, V]) Set(key K, value V) {
+	c.store.Set(key, c.newLoaded(value, nil))
+}
+
+// Delete 使key失效，正在进行的加载的结果不再写入缓存
+func (c *LoadingCache[K, V]) Delete(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	if call, o := c.calls[key]; o {
+		call.invalidated = true
+	}
+	return c.store.Delete(key)
+}
+
+func (c *LoadingCache[K, V]) getLoaded(key K) (*Loaded[V], bool) {
+	l, o := c.store.Get(key)
+	if !o {
+		return nil, false
+	}
+	if !l.expireTime.IsZero() && !time.Now().Before(l.expireTime) {
+		c.store.Delete(key)
+		return nil, false
+	}
+	return l, true
+}
+
+func (c *LoadingCache[K, V]) needRefresh(l *Loaded[V]) bool {
+	return c.refreshAhead > 0 && !l.expireTime.IsZero() && time.Until(l.expireTime) < c.refreshAhead
+}
+
+func (c *LoadingCache[K, V]) newLoaded(v V, err error) *Loaded[V] {
+	l := &Loaded[V]{v: v, err: err}
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.negativeTTL
+	}
+	if ttl > 0 {
+		l.expireTime = time.Now().Add(ttl)
+	}
+	return l
+}
+
+func (c *LoadingCache[K, V]) isLoading(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	_, o := c.calls[key]
+	return o
+}
+
+// load 同一个key同时只有一个加载，其他调用者等待结果
+// refresh为true时是后台刷新，已经在加载则直接返回，失败时保留旧值直到过期
+func (c *LoadingCache[K, V]) load(key K, loader func(K) (V, error), refresh bool) (V, error) {
+	c.locker.Lock()
+	if call, o := c.calls[key]; o {
+		c.locker.Unlock()
+		if refresh {
+			var v V
+			return v, nil
+		}
+		call.wg.Wait()
+		return call.v, call.err
+	}
+	call := &loadCall[V]{}
+	call.wg.Add(1)
+	c.calls[key] = call
+	c.locker.Unlock()
+
+	start := time.Now()
+	defer func() {
+		// loader发生panic时，等待的调用者得到包装了panic的错误，
+		// 前台加载继续panic，后台刷新只记录错误，保留旧值直到过期
+		r := recover()
+		if r != nil {
+			if err, o := r.(error); o {
+				call.err = fmt.Errorf("%w: %w", ErrLoaderPanic, err)
+			} else {
+				call.err = fmt.Errorf("%w: %v", ErrLoaderPanic, r)
+			}
+			c.recordLoad(time.Since(start), call.err)
+		}
+		c.locker.Lock()
+		delete(c.calls, key)
+		c.locker.Unlock()
+		call.wg.Done()
+		if r != nil && !refresh {
+			panic(r)
+		}
+	}()
+	call.v, call.err = loader(key)
+	c.recordLoad(time.Since(start), call.err)
+	c.locker.Lock()
+	if !call.invalidated && (call.err == nil || (!refresh && c.negativeTTL > 0)) {
+		c.store.Set(key, c.newLoaded(call.v, call.err))
+	}
+	c.locker.Unlock()
+	return call.v, call.err
+}
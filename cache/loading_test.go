@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadingCache(t *testing.T) {
+	var (
+		loads  atomic.Int32
+		fail   atomic.Bool
+		errBad = errors.New("bad")
+		c      = NewLoadingCache[int, int](NewLRUWithLock[int, *Loaded[int]](100)).
+			WithTTL(200 * time.Millisecond).
+			WithNegativeTTL(50 * time.Millisecond).
+			WithRefreshAhead(100 * time.Millisecond)
+		loader = func(k int) (int, error) {
+			loads.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			if fail.Load() {
+				return 0, errBad
+			}
+			return k * 10, nil
+		}
+	)
+
+	// 并发的未命中合并为一次加载
+	var wg sync.WaitGroup
+	for g := 0; g < 50; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.GetOrLoad(1, loader); err != nil || v != 10 {
+				t.Errorf("get or load got %v %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if loads.Load() != 1 {
+		t.Fatalf("loads %v, expect 1", loads.Load())
+	}
+
+	// 错误被缓存一小段时间
+	fail.Store(true)
+	if _, err := c.GetOrLoad(2, loader); err != errBad {
+		t.Fatalf("load error got %v", err)
+	}
+	if _, err := c.GetOrLoad(2, loader); err != errBad || loads.Load() != 2 {
+		t.Fatalf("negative cache not work, loads %v", loads.Load())
+	}
+	if _, o := c.Get(2); o {
+		t.Fatalf("error must not be got as value")
+	}
+	time.Sleep(60 * time.Millisecond)
+	fail.Store(false)
+	if v, err := c.GetOrLoad(2, loader); err != nil || v != 20 || loads.Load() != 3 {
+		t.Fatalf("reload after negative ttl got %v %v", v, err)
+	}
+
+	// 快过期时返回旧值并在后台刷新
+	c.Set(3, 0)
+	time.Sleep(120 * time.Millisecond)
+	before := loads.Load()
+	if v, err := c.GetOrLoad(3, loader); err != nil || v != 0 {
+		t.Fatalf("refresh ahead must return old value, got %v %v", v, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if v, o := c.Get(3); !o || v != 30 || loads.Load() != before+1 {
+		t.Fatalf("refresh ahead got %v, loads %v", v, loads.Load()-before)
+	}
+
+	// 过期后重新加载
+	c.Delete(3)
+	if _, o := c.Get(3); o {
+		t.Fatalf("delete failed")
+	}
+	time.Sleep(250 * time.Millisecond)
+	if _, o := c.Get(1); o {
+		t.Fatalf("value must expire after ttl")
+	}
+}
+
+func TestLoadingCachePanic(t *testing.T) {
+	var (
+		c       = NewLoadingCache[int, int](NewLRUWithLock[int, *Loaded[int]](100))
+		release = make(chan struct{})
+		errBoom = errors.New("boom")
+		loader  = func(k int) (int, error) {
+			<-release
+			panic(errBoom)
+		}
+		panicked = make(chan any, 1)
+	)
+	go func() {
+		defer func() { panicked <- recover() }()
+		c.GetOrLoad(1, loader)
+	}()
+	for !c.isLoading(1) {
+		time.Sleep(time.Millisecond)
+	}
+	// 等待同一次加载的调用者得到错误，而不是零值
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			late := func(k int) (int, error) {
+				t.Errorf("waiter loaded key %v itself", k)
+				return 0, nil
+			}
+			if v, err := c.GetOrLoad(1, late); !errors.Is(err, ErrLoaderPanic) || !errors.Is(err, errBoom) {
+				t.Errorf("waiter got %v %v", v, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if r := <-panicked; r != errBoom {
+		t.Fatalf("loader caller recovered %v", r)
+	}
+	if c.isLoading(1) {
+		t.Fatalf("load call not removed after panic")
+	}
+	if v, err := c.GetOrLoad(1, func(k int) (int, error) { return k, nil }); err != nil || v != 1 {
+		t.Fatalf("load after panic got %v %v", v, err)
+	}
+
+	// 后台刷新时panic不会使进程崩溃，保留旧值直到过期
+	r := NewLoadingCache[int, int](NewLRUWithLock[int, *Loaded[int]](100)).
+		WithTTL(100 * time.Millisecond).
+		WithRefreshAhead(90 * time.Millisecond)
+	r.WithStats()
+	r.Set(2, 20)
+	time.Sleep(20 * time.Millisecond)
+	r.GetOrLoad(2, func(k int) (int, error) { panic(errBoom) })
+	for i := 0; r.Stats().LoadErrors == 0 || r.isLoading(2); i++ {
+		if i > 100 {
+			t.Fatalf("refresh panic not recorded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if v, o := r.Get(2); !o || v != 20 {
+		t.Fatalf("old value after refresh panic got %v %v", v, o)
+	}
+}
+
+func TestLoadingCacheDeleteWhileLoading(t *testing.T) {
+	var (
+		c       = NewLoadingCache[int, int](NewLRUWithLock[int, *Loaded[int]](100))
+		release = make(chan struct{})
+		done    = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		if v, err := c.GetOrLoad(1, func(k int) (int, error) {
+			<-release
+			return 10, nil
+		}); err != nil || v != 10 {
+			t.Errorf("get or load got %v %v", v, err)
+		}
+	}()
+	for !c.isLoading(1) {
+		time.Sleep(time.Millisecond)
+	}
+	// 加载过程中被删除，加载的结果返回给调用者，但不写入缓存
+	c.Delete(1)
+	close(release)
+	<-done
+	if _, o := c.Get(1); o {
+		t.Fatalf("stale value written after delete")
+	}
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"sync"
+	"time"
 
 	"github.com/huoshan017/ponu/list"
 )
@@ -19,109 +20,309 @@ func (pair Pair[K, V]) GetValue() V {
 	return pair.v
 }
 
+type lruEntry[K comparable, V any] struct {
+	Pair[K, V]
+	w int64         // 权重
+	e time.Duration // 过期时间点，相对于createTime，0表示不过期
+}
+
 type LRU[K comparable, V any] struct {
-	cap      int32
-	l        list.ListT[Pair[K, V]]
-	m        map[K]list.IteratorT[Pair[K, V]]
-	pairPool *list.ListTNodePool[Pair[K, V]]
+	statsBase
+	cap         int32
+	weigher     func(K, V) int64 // 权重函数，为nil时只按cap限制元素个数
+	maxWeight   int64
+	weight      int64                   // 当前总权重
+	expiredTime time.Duration           // 默认的过期时间，0表示不过期
+	createTime  time.Time               // 创建时间
+	onEvictFun  func(K, V, EvictReason) // 元素被移除时回调
+	reaper      reaper                  // 后台回收过期元素
+	l           list.ListT[lruEntry[K, V]]
+	m           map[K]list.IteratorT[lruEntry[K, V]]
+	entryPool   *list.ListTNodePool[lruEntry[K, V]]
 }
 
 func NewLRU[K comparable, V any](cap int32) *LRU[K, V] {
 	lru := &LRU[K, V]{
-		cap:      cap,
-		m:        make(map[K]list.IteratorT[Pair[K, V]]),
-		pairPool: list.NewListTNodePool[Pair[K, V]](),
+		cap:        cap,
+		createTime: time.Now(),
+		m:          make(map[K]list.IteratorT[lruEntry[K, V]]),
+		entryPool:  list.NewListTNodePool[lruEntry[K, V]](),
 	}
-	lru.l = list.NewListTObjWithPool(lru.pairPool)
+	lru.l = list.NewListTObjWithPool(lru.entryPool)
 	return lru
 }
 
+// WithWeigher 按权重限制容量，总权重超过maxWeight时淘汰最久未访问的元素，需要在使用前设置
+func (lru *LRU[K, V]) WithWeigher(weigher func(K, V) int64, maxWeight int64) {
+	lru.weigher = weigher
+	lru.maxWeight = maxWeight
+}
+
+// WithOnEvict 元素因过期、超出容量、删除、替换被移除时回调
+func (lru *LRU[K, V]) WithOnEvict(fun func(K, V, EvictReason)) {
+	lru.onEvictFun = fun
+}
+
+// WithExpiredtime 设置默认的过期时间，对之后Set的元素生效
+func (lru *LRU[K, V]) WithExpiredtime(t time.Duration) {
+	if t < minExpiredTime {
+		t = minExpiredTime
+	}
+	lru.expiredTime = t
+}
+
+// StartReaper 用scheduler每隔interval回收一次过期的元素，回调需要和LRU的其他操作在同一个协程中执行
+func (lru *LRU[K, V]) StartReaper(scheduler Scheduler, interval time.Duration) bool {
+	return lru.reaper.start(scheduler, interval, func() { lru.DeleteExpired() })
+}
+
+// StopReaper ...
+func (lru *LRU[K, V]) StopReaper() {
+	lru.reaper.stop()
+}
+
+// GetWeight 当前总权重
+func (lru *LRU[K, V]) GetWeight() int64 {
+	return lru.weight
+}
+
+func (lru *LRU[K, V]) weigh(key K, value V) int64 {
+	if lru.weigher == nil {
+		return 0
+	}
+	return lru.weigher(key, value)
+}
+
+// Set 元素超出最大权重时不缓存，返回false
 func (lru *LRU[K, V]) Set(key K, value V) bool {
+	return lru.set(key, value, lru.expiredTime)
+}
+
+// SetExpired 设置元素并指定过期时间
+func (lru *LRU[K, V]) SetExpired(key K, value V, expiredTime time.Duration) bool {
+	if expiredTime <= 0 {
+		expiredTime = minExpiredTime
+	}
+	return lru.set(key, value, expiredTime)
+}
+
+func (lru *LRU[K, V]) set(key K, value V, expiredTime time.Duration) bool {
 	if lru.m == nil {
-		lru.m = make(map[K]list.IteratorT[Pair[K, V]])
+		lru.m = make(map[K]list.IteratorT[lruEntry[K, V]])
 	}
+	w := lru.weigh(key, value)
+	if lru.weigher != nil && w > lru.maxWeight { // 单个元素超出总权重，不缓存，旧的值已经过时
+		lru.delete(key, EvictOverweight)
+		return false
+	}
+	var e time.Duration
+	if expiredTime > 0 {
+		e = time.Since(lru.createTime) + expiredTime
+	}
+	n := lruEntry[K, V]{Pair: Pair[K, V]{k: key, v: value}, w: w, e: e}
 	iter, o := lru.m[key]
 	if !o {
 		if lru.cap > 0 && lru.cap <= lru.l.GetLength() {
-			kv, o := lru.l.PopFront()
-			if !o { // pop front failed
+			if !lru.deleteFront(EvictCapacity) { // pop front failed
 				return false
 			}
-			delete(lru.m, kv.k)
 		}
-		lru.l.PushBack(Pair[K, V]{k: key, v: value})
+		lru.weight += w
+		lru.evictOverweight(key)
+		lru.l.PushBack(n)
 		lru.m[key] = lru.l.RBegin()
 	} else {
-		lru.update(iter, true, value)
+		old := iter.Value()
+		lru.weight += w - old.w
+		lru.moveToBack(iter, n)
+		lru.evictOverweight(key)
+		if lru.onEvictFun != nil {
+			lru.onEvictFun(old.k, old.v, EvictReplaced)
+		}
 	}
 	return true
 }
 
 func (lru *LRU[K, V]) Get(key K) (V, bool) {
-	var (
-		iter list.IteratorT[Pair[K, V]]
-		o    bool
-		v    V
-	)
+	var v V
 	if lru.m == nil {
+		lru.recordGet(false)
 		return v, false
 	}
-	iter, o = lru.m[key]
+	iter, o := lru.m[key]
 	if !o {
+		lru.recordGet(false)
 		return v, false
 	}
-	lru.update(iter, false, v)
-	return iter.Value().v, true
+	n := iter.Value()
+	if lru.isExpired(n) {
+		lru.delete(key, EvictExpired)
+		lru.recordGet(false)
+		return v, false
+	}
+	lru.moveToBack(iter, n)
+	lru.recordGet(true)
+	return n.v, true
 }
 
 func (lru *LRU[K, V]) Has(key K) bool {
 	if lru.m == nil {
 		return false
 	}
-	_, o := lru.m[key]
+	iter, o := lru.m[key]
+	if o && lru.isExpired(iter.Value()) {
+		lru.delete(key, EvictExpired)
+		return false
+	}
 	return o
 }
 
 func (lru *LRU[K, V]) Delete(key K) bool {
-	if lru.m == nil {
-		return false
-	}
-	iter, o := lru.m[key]
-	if !o {
-		return false
+	return lru.delete(key, EvictDeleted)
+}
+
+// Len 元素个数，包括已过期但还没被删除的
+func (lru *LRU[K, V]) Len() int32 {
+	return lru.l.GetLength()
+}
+
+// lruCache LRU.Set返回是否缓存，和Cache接口的Set不同，用这个适配
+type lruCache[K comparable, V any] struct {
+	*LRU[K, V]
+}
+
+func (c lruCache[K, V]) Set(key K, value V) {
+	c.LRU.Set(key, value)
+}
+
+// AsCache 作为Cache接口使用，比如作为Sharded的分片，Set不再返回是否缓存
+func (lru *LRU[K, V]) AsCache() Cache[K, V] {
+	return lruCache[K, V]{lru}
+}
+
+// DeleteExpired 删除所有过期的元素，返回删除的个数
+func (lru *LRU[K, V]) DeleteExpired() int32 {
+	var (
+		num int32
+		now = time.Since(lru.createTime)
+	)
+	for iter := lru.l.Begin(); iter != lru.l.End(); {
+		n := iter.Value()
+		if n.e == 0 || now < n.e {
+			iter = iter.Next()
+			continue
+		}
+		iter, _ = lru.l.DeleteContinueNext(iter)
+		delete(lru.m, n.k)
+		lru.weight -= n.w
+		num++
+		lru.recordEviction()
+		if lru.onEvictFun != nil {
+			lru.onEvictFun(n.k, n.v, EvictExpired)
+		}
 	}
-	delete(lru.m, key)
-	lru.l.Delete(iter)
-	return true
+	return num
 }
 
 func (lru *LRU[K, V]) ToList() list.ListT[V] {
 	var l list.ListT[V]
 	dl := lru.l.Duplicate()
 	for iter := dl.Begin(); iter != dl.End(); iter = iter.Next() {
-		l.PushBack(iter.Value().v)
+		if !lru.isExpired(iter.Value()) {
+			l.PushBack(iter.Value().v)
+		}
 	}
 	dl.Clear()
 	return l
 }
 
+// Range 从最久未访问的元素开始遍历
+func (lru *LRU[K, V]) Range(f func(key K, value V) bool) {
+	for iter := lru.l.Begin(); iter != lru.l.End(); iter = iter.Next() {
+		n := iter.Value()
+		if lru.isExpired(n) {
+			continue
+		}
+		if !f(n.k, n.v) {
+			return
+		}
+	}
+}
+
+func (lru *LRU[K, V]) Keys() []K {
+	return collectKeys(lru.Len(), lru.Range)
+}
+
 func (lru *LRU[K, V]) Clear() {
 	lru.l.Clear()
 	lru.m = nil
+	lru.weight = 0
+}
+
+func (lru *LRU[K, V]) isExpired(n lruEntry[K, V]) bool {
+	return n.e > 0 && time.Since(lru.createTime) >= n.e
+}
+
+func (lru *LRU[K, V]) delete(key K, reason EvictReason) bool {
+	if lru.m == nil {
+		return false
+	}
+	iter, o := lru.m[key]
+	if !o {
+		return false
+	}
+	n := iter.Value()
+	delete(lru.m, key)
+	lru.weight -= n.w
+	lru.l.Delete(iter)
+	if reason == EvictExpired {
+		lru.recordEviction()
+	}
+	if lru.onEvictFun != nil {
+		lru.onEvictFun(n.k, n.v, reason)
+	}
+	return true
+}
+
+func (lru *LRU[K, V]) deleteFront(reason EvictReason) bool {
+	n, o := lru.l.PopFront()
+	if !o {
+		return false
+	}
+	delete(lru.m, n.k)
+	lru.weight -= n.w
+	lru.recordEviction()
+	if lru.onEvictFun != nil {
+		lru.onEvictFun(n.k, n.v, reason)
+	}
+	return true
 }
 
-func (lru *LRU[K, V]) update(iter list.IteratorT[Pair[K, V]], update bool, value V) {
+// evictOverweight 淘汰最久未访问的元素直到总权重不超过maxWeight，不淘汰key
+func (lru *LRU[K, V]) evictOverweight(key K) {
+	if lru.weigher == nil {
+		return
+	}
+	for lru.weight > lru.maxWeight {
+		n, o := lru.l.Front()
+		if !o || n.k == key {
+			return
+		}
+		lru.deleteFront(EvictCapacity)
+	}
+}
+
+// moveToBack 把iter的元素更新为n并移到最近访问的位置
+func (lru *LRU[K, V]) moveToBack(iter list.IteratorT[lruEntry[K, V]], n lruEntry[K, V]) {
 	if iter == lru.l.RBegin() { // keep the origin position
+		lru.l.Update(n, iter)
 		return
 	}
-	n := iter.Value()
 	if !lru.l.Delete(iter) { // delete failed by iter
 		return
 	}
-	if update {
-		n.v = value
-	}
 	lru.l.PushBack(n)
+	lru.m[n.k] = lru.l.RBegin()
 }
 
 type LRUWithLock[K comparable, V any] struct {
@@ -135,6 +336,47 @@ func NewLRUWithLock[K comparable, V any](cap int32) *LRUWithLock[K, V] {
 	}
 }
 
+func (lru *LRUWithLock[K, V]) WithWeigher(weigher func(K, V) int64, maxWeight int64) {
+	lru.locker.Lock()
+	defer lru.locker.Unlock()
+	lru.LRU.WithWeigher(weigher, maxWeight)
+}
+
+func (lru *LRUWithLock[K, V]) WithOnEvict(fun func(K, V, EvictReason)) {
+	lru.locker.Lock()
+	defer lru.locker.Unlock()
+	lru.LRU.WithOnEvict(fun)
+}
+
+func (lru *LRUWithLock[K, V]) WithExpiredtime(t time.Duration) {
+	lru.locker.Lock()
+	defer lru.locker.Unlock()
+	lru.LRU.WithExpiredtime(t)
+}
+
+// StartReaper 回收在加锁后执行，可以在任意协程中执行定时器回调
+func (lru *LRUWithLock[K, V]) StartReaper(scheduler Scheduler, interval time.Duration) bool {
+	return lru.reaper.start(scheduler, interval, func() { lru.DeleteExpired() })
+}
+
+func (lru *LRUWithLock[K, V]) SetExpired(key K, value V, expiredTime time.Duration) {
+	lru.locker.Lock()
+	defer lru.locker.Unlock()
+	lru.LRU.SetExpired(key, value, expiredTime)
+}
+
+func (lru *LRUWithLock[K, V]) DeleteExpired() int32 {
+	lru.locker.Lock()
+	defer lru.locker.Unlock()
+	return lru.LRU.DeleteExpired()
+}
+
+func (lru *LRUWithLock[K, V]) GetWeight() int64 {
+	lru.locker.RLock()
+	defer lru.locker.RUnlock()
+	return lru.LRU.GetWeight()
+}
+
 func (lru *LRUWithLock[K, V]) Set(key K, value V) {
 	lru.locker.Lock()
 	defer lru.locker.Unlock()
@@ -153,10 +395,36 @@ func (lru *LRUWithLock[K, V]) Delete(key K) bool {
 	return lru.LRU.Delete(key)
 }
 
+// Has 过期的元素会被删除，所以要加写锁
 func (lru *LRUWithLock[K, V]) Has(key K) bool {
+	lru.locker.Lock()
+	defer lru.locker.Unlock()
+	return lru.LRU.Has(key)
+}
+
+func (lru *LRUWithLock[K, V]) Len() int32 {
 	lru.locker.RLock()
 	defer lru.locker.RUnlock()
-	return lru.LRU.Has(key)
+	return lru.LRU.Len()
+}
+
+func (lru *LRUWithLock[K, V]) Clear() {
+	lru.locker.Lock()
+	defer lru.locker.Unlock()
+	lru.LRU.Clear()
+}
+
+// Range 遍历时持有读锁
+func (lru *LRUWithLock[K, V]) Range(f func(key K, value V) bool) {
+	lru.locker.RLock()
+	defer lru.locker.RUnlock()
+	lru.LRU.Range(f)
+}
+
+func (lru *LRUWithLock[K, V]) Keys() []K {
+	lru.locker.RLock()
+	defer lru.locker.RUnlock()
+	return lru.LRU.Keys()
 }
 
 func (lru *LRUWithLock[K, V]) ToList() list.ListT[V] {
@@ -1,6 +1,13 @@
 package cache
 
-import "testing"
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ptime "github.com/huoshan017/ponu/time"
+)
 
 func TestLRU(t *testing.T) {
 	l := NewLRU[int, int](10)
@@ -24,3 +31,157 @@ func TestLRU(t *testing.T) {
 		iter = iter.Next()
 	}
 }
+
+func TestLRUWeigher(t *testing.T) {
+	l := NewLRU[int, string](0)
+	l.WithWeigher(func(k int, v string) int64 { return int64(len(v)) }, 10)
+	l.Set(1, "aaa")
+	l.Set(2, "bbb")
+	l.Set(3, "ccc")
+	l.Get(1)
+	// 2最久未访问，被淘汰
+	l.Set(4, "dd")
+	if l.Has(2) || !l.Has(1) || l.GetWeight() != 8 {
+		t.Fatalf("evict by weight failed, weight %v", l.GetWeight())
+	}
+	// 更新变重，淘汰其他元素但不淘汰自己
+	l.Set(4, "dddddddd")
+	if !l.Has(4) || l.Has(1) || l.Has(3) || l.GetWeight() != 8 {
+		t.Fatalf("evict by update weight failed, weight %v", l.GetWeight())
+	}
+	if l.Set(5, "eeeeeeeeeee") || l.Has(5) {
+		t.Fatalf("value heavier than max weight must not be cached")
+	}
+	if v, o := l.Get(4); !o || v != "dddddddd" {
+		t.Fatalf("get 4 got %v", v)
+	}
+	// 已有的key被设置为超出最大权重的值时，旧的值被移除，原因不是替换
+	var reason EvictReason = -1
+	l.WithOnEvict(func(k int, v string, r EvictReason) { reason = r })
+	if l.Set(4, "dddddddddddd") || l.Has(4) || reason != EvictOverweight {
+		t.Fatalf("overweight update of key 4, reason %v", reason)
+	}
+	l.WithOnEvict(nil)
+	l.Set(4, "dddddddd")
+	l.Delete(4)
+	if l.GetWeight() != 0 {
+		t.Fatalf("weight after delete %v", l.GetWeight())
+	}
+}
+
+func TestLRUExpire(t *testing.T) {
+	reasons := make(map[int]EvictReason)
+	l := NewLRU[int, int](3)
+	l.WithOnEvict(func(k, v int, reason EvictReason) { reasons[k] = reason })
+	l.SetExpired(1, 1, 50*time.Millisecond)
+	l.Set(2, 2)
+	l.Set(2, 20)
+	l.Set(3, 3)
+	l.Set(4, 4)
+	l.Delete(3)
+	if reasons[1] != EvictCapacity || reasons[2] != EvictReplaced || reasons[3] != EvictDeleted {
+		t.Fatalf("evict reasons %v", reasons)
+	}
+
+	l.WithExpiredtime(time.Second)
+	l.SetExpired(5, 5, 50*time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	if l.Has(5) || reasons[5] != EvictExpired {
+		t.Fatalf("lazy expire by Has failed")
+	}
+	l.SetExpired(6, 6, 50*time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	if _, o := l.Get(6); o {
+		t.Fatalf("lazy expire by Get failed")
+	}
+
+	// 后台回收
+	w := ptime.NewSWheel(time.Second, ptime.WithInterval(10*time.Millisecond))
+	w.Start()
+	ll := NewLRUWithLock[int, int](0)
+	var expired atomic.Int32
+	ll.WithOnEvict(func(k, v int, reason EvictReason) {
+		if reason == EvictExpired {
+			expired.Add(1)
+		}
+	})
+	for i := 0; i < 100; i++ {
+		ll.SetExpired(i, i, time.Duration(i%2+1)*50*time.Millisecond)
+	}
+	ll.Set(100, 100)
+	if !ll.StartReaper(w, 20*time.Millisecond) {
+		t.Fatalf("start reaper failed")
+	}
+	for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); {
+		w.Update()
+		time.Sleep(time.Millisecond)
+	}
+	ll.StopReaper()
+	if expired.Load() != 100 || ll.l.GetLength() != 1 || !ll.Has(100) {
+		t.Fatalf("reaper expired %v, length %v", expired.Load(), ll.l.GetLength())
+	}
+}
+
+// afterFuncScheduler 用time.AfterFunc在其他协程中执行定时器回调
+type afterFuncScheduler struct {
+	locker sync.Mutex
+	nextId uint32
+	timers map[uint32]*time.Timer
+}
+
+func (s *afterFuncScheduler) Add(timeout time.Duration, fun ptime.TimerFunc, args []any) uint32 {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	s.nextId++
+	id := s.nextId
+	s.timers[id] = time.AfterFunc(timeout, func() {
+		s.locker.Lock()
+		delete(s.timers, id)
+		s.locker.Unlock()
+		fun(id, args)
+	})
+	return id
+}
+
+func (s *afterFuncScheduler) Cancel(id uint32) {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	if t, o := s.timers[id]; o {
+		t.Stop()
+		delete(s.timers, id)
+	}
+}
+
+func (s *afterFuncScheduler) armed() int {
+	s.locker.Lock()
+	defer s.locker.Unlock()
+	return len(s.timers)
+}
+
+func TestLRUReaperRestart(t *testing.T) {
+	s := &afterFuncScheduler{timers: make(map[uint32]*time.Timer)}
+	ll := NewLRUWithLock[int, int](100)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			ll.SetExpired(i%100, i, time.Millisecond)
+		}
+	}()
+	// 重新启动和停止时取消之前的定时器，不留下无效的定时器
+	for i := 0; i < 100; i++ {
+		if !ll.StartReaper(s, time.Millisecond) {
+			t.Fatalf("start reaper failed")
+		}
+		time.Sleep(100 * time.Microsecond)
+		if n := s.armed(); n > 1 {
+			t.Fatalf("%v timers armed after restart", n)
+		}
+		ll.StopReaper()
+	}
+	wg.Wait()
+	if n := s.armed(); n != 0 {
+		t.Fatalf("%v timers armed after stop", n)
+	}
+}
@@ -0,0 +1,393 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/huoshan017/ponu/codec"
+	"github.com/huoshan017/ponu/list"
+)
+
+const snapshotVersion = 1
+
+const (
+	snapshotKindLRU int64 = iota + 1
+	snapshotKindLFU
+)
+
+var (
+	ErrSnapshotVersion  = errors.New("ponu cache: snapshot version not supported")
+	ErrSnapshotMismatch = errors.New("ponu cache: snapshot kind not match")
+	ErrSnapshotCorrupt  = errors.New("ponu cache: snapshot corrupt")
+)
+
+// 快照格式(整数都是varint)：
+// version kind saveTime age count, 然后每个元素是 keyLen key valueLen value ttl freq
+// saveTime是保存时的unix纳秒，ttl是保存时剩余的过期时间，0表示不过期，LRU的age和freq都是0
+// LRU按从最久未访问到最近访问的顺序，LFU按访问频次从低到高的顺序
+// ConcurrentLFU合并所有shard的元素，保存为LFU的快照
+// Sharded不支持快照：默认的哈希函数每个实例的种子不同，分片也可以是任意的缓存，
+// 需要时可以用Range导出后在新的实例中Set，访问顺序和过期时间不保留
+
+type snapshotWriter struct {
+	bw  *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func newSnapshotWriter(w io.Writer, kind, age, count int64) (*snapshotWriter, error) {
+	sw := &snapshotWriter{bw: bufio.NewWriter(w)}
+	for _, v := range []int64{snapshotVersion, kind, time.Now().UnixNano(), age, count} {
+		if err := sw.writeVarint(v); err != nil {
+			return nil, err
+		}
+	}
+	return sw, nil
+}
+
+func (sw *snapshotWriter) writeVarint(v int64) error {
+	_, err := sw.bw.Write(sw.buf[:binary.PutVarint(sw.buf[:], v)])
+	return err
+}
+
+func (sw *snapshotWriter) writeBytes(data []byte) error {
+	if err := sw.writeVarint(int64(len(data))); err != nil {
+		return err
+	}
+	_, err := sw.bw.Write(data)
+	return err
+}
+
+// writeSnapshot 元素先收集好再写入，保证头部的count和写入的元素个数一致
+func writeSnapshot[K comparable, V any](w io.Writer, kind, age int64, entries []snapshotEntry[K, V], keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	sw, err := newSnapshotWriter(w, kind, age, int64(len(entries)))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err = writeEntry(sw, keyCodec, valueCodec, e); err != nil {
+			return err
+		}
+	}
+	return sw.bw.Flush()
+}
+
+func writeEntry[K comparable, V any](sw *snapshotWriter, keyCodec codec.Codec[K], valueCodec codec.Codec[V], e snapshotEntry[K, V]) error {
+	data, err := keyCodec.Marshal(e.key)
+	if err != nil {
+		return err
+	}
+	if err = sw.writeBytes(data); err != nil {
+		return err
+	}
+	if data, err = valueCodec.Marshal(e.value); err != nil {
+		return err
+	}
+	if err = sw.writeBytes(data); err != nil {
+		return err
+	}
+	if err = sw.writeVarint(int64(e.ttl)); err != nil {
+		return err
+	}
+	return sw.writeVarint(int64(e.f))
+}
+
+type snapshotReader struct {
+	*codec.Reader
+	elapsed time.Duration // 保存之后经过的时间
+	age     int64
+	count   int64
+}
+
+func newSnapshotReader(r io.Reader, kind int64) (*snapshotReader, error) {
+	sr := &snapshotReader{Reader: codec.NewReader(r)}
+	var header [5]int64
+	for i := range header {
+		v, err := sr.ReadVarint()
+		if err != nil {
+			return nil, err
+		}
+		header[i] = v
+	}
+	if header[0] != snapshotVersion {
+		return nil, ErrSnapshotVersion
+	}
+	if header[1] != kind {
+		return nil, ErrSnapshotMismatch
+	}
+	if header[3] < 0 || header[4] < 0 {
+		return nil, ErrSnapshotCorrupt
+	}
+	sr.elapsed = time.Since(time.Unix(0, header[2]))
+	if sr.elapsed < 0 {
+		sr.elapsed = 0
+	}
+	sr.age, sr.count = header[3], header[4]
+	return sr, nil
+}
+
+// readBytes 长度不合法时返回ErrSnapshotCorrupt
+func (sr *snapshotReader) readBytes() ([]byte, error) {
+	data, err := sr.ReadBytes()
+	if err == codec.ErrLength {
+		err = ErrSnapshotCorrupt
+	}
+	return data, err
+}
+
+type snapshotEntry[K comparable, V any] struct {
+	key   K
+	value V
+	ttl   time.Duration // 保存时剩余的过期时间，读取后扣除保存之后经过的时间，小于0表示已过期
+	f     int32
+}
+
+func readEntry[K comparable, V any](sr *snapshotReader, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) (snapshotEntry[K, V], error) {
+	var e snapshotEntry[K, V]
+	data, err := sr.readBytes()
+	if err != nil {
+		return e, err
+	}
+	if e.key, err = keyCodec.Unmarshal(data); err != nil {
+		return e, err
+	}
+	if data, err = sr.readBytes(); err != nil {
+		return e, err
+	}
+	if e.value, err = valueCodec.Unmarshal(data); err != nil {
+		return e, err
+	}
+	ttl, err := sr.ReadVarint()
+	if err != nil {
+		return e, err
+	}
+	if ttl < 0 {
+		return e, ErrSnapshotCorrupt
+	}
+	if e.ttl = time.Duration(ttl); e.ttl > 0 {
+		if e.ttl -= sr.elapsed; e.ttl <= 0 {
+			e.ttl = -1
+		}
+	}
+	f, err := sr.ReadVarint()
+	if err != nil {
+		return e, err
+	}
+	if f < 0 || f > int64(^uint32(0)>>1) {
+		return e, ErrSnapshotCorrupt
+	}
+	e.f = int32(f)
+	return e, nil
+}
+
+// Save 按从最久未访问到最近访问的顺序把未过期的元素写入w，key和value分别用keyCodec和valueCodec编码
+func (lru *LRU[K, V]) Save(w io.Writer, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	var (
+		entries = make([]snapshotEntry[K, V], 0, lru.Len())
+		now     = time.Since(lru.createTime)
+	)
+	for iter := lru.l.Begin(); iter != lru.l.End(); iter = iter.Next() {
+		n := iter.Value()
+		if lru.isExpired(n) {
+			continue
+		}
+		var ttl time.Duration
+		if n.e > 0 {
+			ttl = n.e - now
+		}
+		entries = append(entries, snapshotEntry[K, V]{key: n.k, value: n.v, ttl: ttl})
+	}
+	return writeSnapshot(w, snapshotKindLRU, 0, entries, keyCodec, valueCodec)
+}
+
+// Load 用r中的快照替换LRU的内容，保持原来的访问顺序和剩余的过期时间，保存之后已过期的元素被丢弃
+// 快照的元素超出容量时淘汰最久未访问的，读取出错时LRU可能只加载了部分元素
+func (lru *LRU[K, V]) Load(r io.Reader, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	sr, err := newSnapshotReader(r, snapshotKindLRU)
+	if err != nil {
+		return err
+	}
+	lru.Clear()
+	for i := int64(0); i < sr.count; i++ {
+		e, err := readEntry(sr, keyCodec, valueCodec)
+		if err != nil {
+			return err
+		}
+		if _, o := lru.m[e.key]; o {
+			return ErrSnapshotCorrupt
+		}
+		if e.ttl < 0 {
+			continue
+		}
+		lru.set(e.key, e.value, e.ttl)
+	}
+	return nil
+}
+
+// Save 按访问频次从低到高的顺序把未过期的元素和频次写入w
+func (lfu *lfuBase[K, V]) Save(w io.Writer, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	return writeSnapshot(w, snapshotKindLFU, int64(lfu.age), lfu.snapshotEntries(), keyCodec, valueCodec)
+}
+
+// snapshotEntries 按访问频次从低到高收集未过期的元素
+func (lfu *lfuBase[K, V]) snapshotEntries() []snapshotEntry[K, V] {
+	var (
+		entries = make([]snapshotEntry[K, V], 0, lfu.l.GetLength())
+		now     = time.Now()
+	)
+	for iter := lfu.l.Begin(); iter != lfu.l.End(); iter = iter.Next() {
+		n := iter.Value().(node[K, V])
+		var ttl time.Duration
+		if d, o := lfu.k2t[n.k]; o {
+			if lfu.isExpired(now, d) {
+				continue
+			}
+			ttl = d - now.Sub(lfu.createTime)
+		}
+		entries = append(entries, snapshotEntry[K, V]{key: n.k, value: n.v, ttl: ttl, f: n.f})
+	}
+	return entries
+}
+
+// Load 用r中的快照替换LFU的内容，保持原来的访问频次和剩余的过期时间，保存之后已过期的元素被丢弃
+// 快照的元素超出容量时淘汰频次最低的，读取出错时LFU可能只加载了部分元素
+func (lfu *lfuBase[K, V]) Load(r io.Reader, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	sr, err := newSnapshotReader(r, snapshotKindLFU)
+	if err != nil {
+		return err
+	}
+	lfu.reset(sr.age)
+	var last int32
+	for i := int64(0); i < sr.count; i++ {
+		e, err := readEntry(sr, keyCodec, valueCodec)
+		if err != nil {
+			return err
+		}
+		if e.f < last {
+			return ErrSnapshotCorrupt
+		}
+		last = e.f
+		if _, o := lfu.k2i[e.key]; o {
+			return ErrSnapshotCorrupt
+		}
+		if e.ttl < 0 {
+			continue
+		}
+		lfu.load(e.key, e.value, e.f, e.ttl)
+	}
+	return nil
+}
+
+// reset 加载之前清空，age是快照中的老化基准
+func (lfu *lfuBase[K, V]) reset(age int64) {
+	lfu.Clear()
+	lfu.k2i = make(map[K]list.Iterator)
+	lfu.f2i = make(map[int32]list.Iterator)
+	lfu.k2t = make(map[K]time.Duration)
+	lfu.age = 0
+	if age <= int64(^uint32(0)>>1) {
+		lfu.age = int32(age)
+	}
+}
+
+// load 按频次从低到高的顺序加载，新元素总是在链表尾
+func (lfu *lfuBase[K, V]) load(key K, value V, f int32, ttl time.Duration) {
+	w := lfu.weigh(key, value)
+	if w > lfu.limit() {
+		return
+	}
+	lfu.evictOverweight(lfu.addSize(w), key)
+	if f < 1 {
+		f = 1
+	}
+	lfu.l.PushBack(node[K, V]{Pair: Pair[K, V]{k: key, v: value}, f: f, w: w})
+	iter := lfu.l.RBegin()
+	lfu.f2i[f] = iter
+	lfu.k2i[key] = iter
+	if ttl > 0 {
+		lfu.k2t[key] = lfu.getExpiredTimePoint(time.Now(), ttl)
+	}
+}
+
+// Save 保存时持有读锁
+func (lru *LRUWithLock[K, V]) Save(w io.Writer, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	lru.locker.RLock()
+	defer lru.locker.RUnlock()
+	return lru.LRU.Save(w, keyCodec, valueCodec)
+}
+
+func (lru *LRUWithLock[K, V]) Load(r io.Reader, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	lru.locker.Lock()
+	defer lru.locker.Unlock()
+	return lru.LRU.Load(r, keyCodec, valueCodec)
+}
+
+// Save 保存时持有读锁
+func (lfu *LFUWithLock[K, V]) Save(w io.Writer, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	lfu.rwlock.RLock()
+	defer lfu.rwlock.RUnlock()
+	return lfu.lfuBase.Save(w, keyCodec, valueCodec)
+}
+
+func (lfu *LFUWithLock[K, V]) Load(r io.Reader, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	lfu.rwlock.Lock()
+	defer lfu.rwlock.Unlock()
+	return lfu.lfuBase.Load(r, keyCodec, valueCodec)
+}
+
+// Save 把所有shard的元素按访问频次从低到高合并后写入w，格式和LFU的快照相同
+// 逐个shard加读锁收集，不是原子的快照
+func (lfu *ConcurrentLFU[K, V]) Save(w io.Writer, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	var (
+		entries []snapshotEntry[K, V]
+		age     int32
+	)
+	for _, shard := range lfu.shards {
+		shard.rwlock.RLock()
+		entries = append(entries, shard.snapshotEntries()...)
+		age = max(age, shard.age)
+		shard.rwlock.RUnlock()
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].f < entries[j].f })
+	return writeSnapshot(w, snapshotKindLFU, int64(age), entries, keyCodec, valueCodec)
+}
+
+// Load 用r中的快照替换所有shard的内容，元素按key重新分配到shard，也可以加载LFU保存的快照
+// 逐个shard加锁加载，读取出错时可能只加载了部分元素
+func (lfu *ConcurrentLFU[K, V]) Load(r io.Reader, keyCodec codec.Codec[K], valueCodec codec.Codec[V]) error {
+	sr, err := newSnapshotReader(r, snapshotKindLFU)
+	if err != nil {
+		return err
+	}
+	for _, shard := range lfu.shards {
+		shard.rwlock.Lock()
+		shard.reset(sr.age)
+		shard.rwlock.Unlock()
+	}
+	var last int32
+	for i := int64(0); i < sr.count; i++ {
+		e, err := readEntry(sr, keyCodec, valueCodec)
+		if err != nil {
+			return err
+		}
+		if e.f < last {
+			return ErrSnapshotCorrupt
+		}
+		last = e.f
+		// 合并后的顺序是按频次从低到高的，分配到每个shard的元素也是
+		shard := lfu.shards[lfu.getHashIndex(e.key)]
+		shard.rwlock.Lock()
+		_, dup := shard.k2i[e.key]
+		if !dup && e.ttl >= 0 {
+			shard.load(e.key, e.value, e.f, e.ttl)
+		}
+		shard.rwlock.Unlock()
+		if dup {
+			return ErrSnapshotCorrupt
+		}
+	}
+	return nil
+}
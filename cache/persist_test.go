@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/huoshan017/ponu/codec"
+)
+
+func equalKeys[K comparable](t *testing.T, name string, got, expect []K) {
+	if len(got) != len(expect) {
+		t.Fatalf("%v got %v, expect %v", name, got, expect)
+	}
+	for i := range got {
+		if got[i] != expect[i] {
+			t.Fatalf("%v got %v, expect %v", name, got, expect)
+		}
+	}
+}
+
+func TestLRUSaveLoad(t *testing.T) {
+	var (
+		kc  = codec.Binary[int32]{}
+		vc  = codec.JSON[string]{}
+		buf bytes.Buffer
+	)
+	l := NewLRU[int32, string](10)
+	for k := int32(1); k <= 5; k++ {
+		l.Set(k, "v")
+	}
+	l.Get(2)
+	l.SetExpired(6, "short", 50*time.Millisecond)
+	l.SetExpired(7, "long", time.Hour)
+	if err := l.Save(&buf, kc, vc); err != nil {
+		t.Fatalf("save err: %v", err)
+	}
+	data := buf.Bytes()
+	time.Sleep(60 * time.Millisecond)
+
+	n := NewLRU[int32, string](10)
+	if err := n.Load(bytes.NewReader(data), kc, vc); err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	// 保存之后过期的元素被丢弃
+	equalKeys(t, "recency", n.Keys(), []int32{1, 3, 4, 5, 2, 7})
+	if iter := n.l.RBegin(); iter.Value().e == 0 {
+		t.Fatalf("ttl of key 7 lost")
+	}
+
+	// 容量不够时淘汰最久未访问的
+	s := NewLRU[int32, string](3)
+	if err := s.Load(bytes.NewReader(data), kc, vc); err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	equalKeys(t, "truncate", s.Keys(), []int32{5, 2, 7})
+
+	if err := NewLFU[int32, string](10).Load(bytes.NewReader(data), kc, vc); err != ErrSnapshotMismatch {
+		t.Fatalf("load lru snapshot into lfu got %v", err)
+	}
+	if err := n.Load(bytes.NewReader(data[:len(data)-3]), kc, vc); err != io.ErrUnexpectedEOF {
+		t.Fatalf("load truncated snapshot got %v", err)
+	}
+}
+
+func TestLFUSaveLoad(t *testing.T) {
+	var (
+		kc  = codec.JSON[int]{}
+		vc  = codec.JSON[int]{}
+		buf bytes.Buffer
+	)
+	l := NewLFU[int, int](8)
+	for k := 1; k <= 5; k++ {
+		l.Set(k, k*10)
+	}
+	for i := 0; i < 3; i++ {
+		l.Get(1)
+	}
+	l.Get(2)
+	l.Get(2)
+	l.SetExpired(6, 60, 50*time.Millisecond)
+	expect := l.Keys()
+	if err := l.Save(&buf, kc, vc); err != nil {
+		t.Fatalf("save err: %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	n := NewLFUWithLock[int, int](8)
+	if err := n.Load(&buf, kc, vc); err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	equalKeys(t, "frequency", n.Keys(), []int{3, 4, 5, 2, 1})
+	if len(expect) != 6 || expect[3] != 6 {
+		t.Fatalf("keys before save %v", expect)
+	}
+	if v, o := n.Get(1); !o || v != 10 {
+		t.Fatalf("get 1 got %v %v", v, o)
+	}
+	// 频次高的元素在加载后不会被新元素淘汰
+	for k := 100; k < 120; k++ {
+		n.Set(k, k)
+	}
+	if !n.Has(1) || !n.Has(2) || n.Has(3) || n.Len() != 8 {
+		t.Fatalf("keys after set %v", n.Keys())
+	}
+	checkLFUOrder(t, n.lfuBase)
+}
+
+func TestConcurrentLFUSaveLoad(t *testing.T) {
+	var (
+		kc  = codec.String{}
+		vc  = codec.JSON[int]{}
+		buf bytes.Buffer
+	)
+	l := NewConcurrentLFU[string, int](100)
+	freq := make(map[string]int32)
+	for i := 0; i < 50; i++ {
+		k := fmt.Sprint("key", i)
+		l.Set(k, i)
+		freq[k] = 1
+		for j := 0; j < i%5; j++ {
+			l.Get(k)
+			freq[k]++
+		}
+	}
+	l.shards[0].SetExpired("short", 1, 50*time.Millisecond)
+	if err := l.Save(&buf, kc, vc); err != nil {
+		t.Fatalf("save err: %v", err)
+	}
+	data := buf.Bytes()
+	time.Sleep(60 * time.Millisecond)
+
+	check := func(name string, n *ConcurrentLFU[string, int]) {
+		if n.Len() != 50 || n.Has("short") {
+			t.Fatalf("%v length %v after load", name, n.Len())
+		}
+		for _, shard := range n.shards {
+			for iter := shard.l.Begin(); iter != shard.l.End(); iter = iter.Next() {
+				nd := iter.Value().(node[string, int])
+				if nd.f != freq[nd.k] || n.getHashIndex(nd.k) != int32(indexOf(n.shards, shard)) {
+					t.Fatalf("%v key %v freq %v, expect %v", name, nd.k, nd.f, freq[nd.k])
+				}
+			}
+		}
+	}
+	n := NewConcurrentLFU[string, int](100)
+	n.Set("old", 0)
+	if err := n.Load(bytes.NewReader(data), kc, vc); err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	check("concurrent", n)
+
+	// ConcurrentLFU的快照和LFU的可以互相加载
+	m := NewLFU[string, int](100)
+	if err := m.Load(bytes.NewReader(data), kc, vc); err != nil || m.Len() != 50 {
+		t.Fatalf("load into lfu got %v, length %v", err, m.Len())
+	}
+	buf.Reset()
+	m.Save(&buf, kc, vc)
+	n = NewConcurrentLFU[string, int](100)
+	if err := n.Load(&buf, kc, vc); err != nil {
+		t.Fatalf("load lfu snapshot err: %v", err)
+	}
+	check("from lfu", n)
+}
+
+func indexOf[T comparable](s []T, v T) int {
+	for i := range s {
+		if s[i] == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	header := func(count int64) []byte {
+		var b []byte
+		for _, v := range []int64{snapshotVersion, snapshotKindLRU, time.Now().UnixNano(), 0, count} {
+			b = binary.AppendVarint(b, v)
+		}
+		return b
+	}
+	kc, vc := codec.Binary[int32]{}, codec.String{}
+	// 两个相同的key：keyLen key valueLen value ttl freq
+	dup := header(2)
+	for i := 0; i < 2; i++ {
+		dup = binary.AppendVarint(dup, 4)
+		dup = append(dup, 1, 0, 0, 0)
+		dup = binary.AppendVarint(dup, 1)
+		dup = append(dup, 'v')
+		dup = binary.AppendVarint(dup, 0)
+		dup = binary.AppendVarint(dup, 0)
+	}
+	for _, c := range []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"huge count", header(math.MaxInt64), io.ErrUnexpectedEOF},
+		{"huge key length", binary.AppendVarint(header(1), math.MaxInt64), io.ErrUnexpectedEOF},
+		{"negative key length", binary.AppendVarint(header(1), -1), ErrSnapshotCorrupt},
+		{"truncated key", append(binary.AppendVarint(header(1), 1<<20), 1, 2, 3), io.ErrUnexpectedEOF},
+		{"duplicate key", dup, ErrSnapshotCorrupt},
+	} {
+		if err := NewLRU[int32, string](10).Load(bytes.NewReader(c.data), kc, vc); err != c.err {
+			t.Fatalf("%v load got %v, expect %v", c.name, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	ptime "github.com/huoshan017/ponu/time"
+)
+
+// EvictReason 元素被移除的原因
+type EvictReason int8
+
+const (
+	EvictExpired    EvictReason = iota // 过期
+	EvictCapacity                      // 超出容量
+	EvictDeleted                       // 主动删除
+	EvictReplaced                      // 被新的值替换
+	EvictOverweight                    // 新的值超出最大权重没有缓存，旧的值被移除
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictExpired:
+		return "expired"
+	case EvictCapacity:
+		return "capacity"
+	case EvictDeleted:
+		return "deleted"
+	case EvictReplaced:
+		return "replaced"
+	case EvictOverweight:
+		return "overweight"
+	}
+	return "unknown"
+}
+
+// Scheduler 见time.Scheduler
+type Scheduler = ptime.Scheduler
+
+// reaper 用时间轮定时回收过期的元素
+// 定时器回调可能在其他协程中执行，字段都由locker保护，回收时不持有locker
+type reaper struct {
+	locker    sync.Mutex
+	scheduler Scheduler
+	interval  time.Duration
+	timerId   uint32
+	running   bool
+	reap      func()
+}
+
+func (r *reaper) start(scheduler Scheduler, interval time.Duration, reap func()) bool {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	r.stopLocked()
+	r.scheduler = scheduler
+	r.interval = interval
+	r.reap = reap
+	r.running = true
+	if !r.schedule() {
+		r.running = false
+		return false
+	}
+	return true
+}
+
+// stop 取消已添加的定时器，scheduler不支持取消时定时器到期后不再处理
+func (r *reaper) stop() {
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	r.stopLocked()
+}
+
+func (r *reaper) stopLocked() {
+	r.running = false
+	if r.timerId != 0 {
+		ptime.CancelTimer(r.scheduler, r.timerId)
+		r.timerId = 0
+	}
+}
+
+func (r *reaper) schedule() bool {
+	r.timerId = r.scheduler.Add(r.interval, r.onTimer, nil)
+	return r.timerId != 0
+}
+
+func (r *reaper) onTimer(id uint32, args []any) {
+	r.locker.Lock()
+	if !r.running || id != r.timerId {
+		r.locker.Unlock()
+		return
+	}
+	reap := r.reap
+	r.locker.Unlock()
+	reap()
+	r.locker.Lock()
+	defer r.locker.Unlock()
+	// 回收时可能被停止或者重新启动
+	if r.running && id == r.timerId {
+		r.schedule()
+	}
+}
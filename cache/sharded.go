@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"sync"
+)
+
+const defaultShardNum = 64
+
+type cacheShard[K comparable, V any] struct {
+	locker sync.Mutex
+	c      Cache[K, V]
+}
+
+// Sharded 按key的哈希分片的线程安全缓存，每个分片加锁访问，分片可以使用任意的淘汰策略
+// 不支持Save和Load，见persist.go中快照格式的说明
+type Sharded[K comparable, V any] struct {
+	hasher func(K) uint64
+	mask   uint64
+	shards []cacheShard[K, V]
+}
+
+// NewSharded shardNum向上取整为2的幂，小于等于0时使用默认的64，newShard创建每个分片的缓存
+// 使用maphash实现的默认哈希函数，每个实例的随机种子不同
+func NewSharded[K comparable, V any](shardNum int, newShard func() Cache[K, V]) *Sharded[K, V] {
+	return NewShardedWithHasher(shardNum, NewHasher[K](), newShard)
+}
+
+// NewShardedWithHasher 使用自定义的哈希函数
+func NewShardedWithHasher[K comparable, V any](shardNum int, hasher func(K) uint64, newShard func() Cache[K, V]) *Sharded[K, V] {
+	if hasher == nil || newShard == nil {
+		panic("ponu cache: Sharded need hasher and new shard function")
+	}
+	n := roundShardNum(shardNum)
+	s := &Sharded[K, V]{
+		hasher: hasher,
+		mask:   uint64(n - 1),
+		shards: make([]cacheShard[K, V], n),
+	}
+	for i := range s.shards {
+		s.shards[i].c = newShard()
+	}
+	return s
+}
+
+// roundShardNum 向上取整为2的幂，小于等于0时使用默认值
+func roundShardNum(shardNum int) int {
+	if shardNum <= 0 {
+		shardNum = defaultShardNum
+	}
+	n := 1
+	for n < shardNum {
+		n <<= 1
+	}
+	return n
+}
+
+func (s *Sharded[K, V]) getShard(key K) *cacheShard[K, V] {
+	return &s.shards[s.hasher(key)&s.mask]
+}
+
+func (s *Sharded[K, V]) Get(key K) (V, bool) {
+	shard := s.getShard(key)
+	shard.locker.Lock()
+	defer shard.locker.Unlock()
+	return shard.c.Get(key)
+}
+
+func (s *Sharded[K, V]) Set(key K, value V) {
+	shard := s.getShard(key)
+	shard.locker.Lock()
+	defer shard.locker.Unlock()
+	shard.c.Set(key, value)
+}
+
+func (s *Sharded[K, V]) Delete(key K) bool {
+	shard := s.getShard(key)
+	shard.locker.Lock()
+	defer shard.locker.Unlock()
+	return shard.c.Delete(key)
+}
+
+// Has 过期的元素可能在Has时被删除，所以也加锁
+func (s *Sharded[K, V]) Has(key K) bool {
+	shard := s.getShard(key)
+	shard.locker.Lock()
+	defer shard.locker.Unlock()
+	return shard.c.Has(key)
+}
+
+// Len 所有分片的元素个数之和，不是原子的快照
+func (s *Sharded[K, V]) Len() int32 {
+	var n int32
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.locker.Lock()
+		n += shard.c.Len()
+		shard.locker.Unlock()
+	}
+	return n
+}
+
+func (s *Sharded[K, V]) Clear() {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.locker.Lock()
+		shard.c.Clear()
+		shard.locker.Unlock()
+	}
+}
+
+// Range 逐个分片加锁遍历，不是原子的快照
+func (s *Sharded[K, V]) Range(f func(key K, value V) bool) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.locker.Lock()
+		goon := true
+		shard.c.Range(func(k K, v V) bool {
+			goon = f(k, v)
+			return goon
+		})
+		shard.locker.Unlock()
+		if !goon {
+			return
+		}
+	}
+}
+
+func (s *Sharded[K, V]) Keys() []K {
+	return collectKeys(s.Len(), s.Range)
+}
+
+// GetShardNum ...
+func (s *Sharded[K, V]) GetShardNum() int {
+	return len(s.shards)
+}
+
+// ShardDo 在加锁的情况下对每个分片执行f，可以用来做统计等操作
+func (s *Sharded[K, V]) ShardDo(f func(index int, c Cache[K, V])) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.locker.Lock()
+		f(i, shard.c)
+		shard.locker.Unlock()
+	}
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+type shardedKey struct {
+	id   int64
+	name string
+	tag  any
+}
+
+func TestHasher(t *testing.T) {
+	h := NewHasher[shardedKey]()
+	if h(shardedKey{1, "a", 2}) != h(shardedKey{1, "a", 2}) {
+		t.Fatalf("equal struct keys got different hash")
+	}
+	if h(shardedKey{1, "a", 2}) == h(shardedKey{1, "a", int64(2)}) {
+		t.Fatalf("interface field with different dynamic type got same hash")
+	}
+	f := NewHasher[float64]()
+	if f(0) != f(math.Copysign(0, -1)) {
+		t.Fatalf("+0 and -0 got different hash")
+	}
+	s := NewHasher[string]()
+	if s("abc") != s("abc") || s("abc") == s("abd") {
+		t.Fatalf("string hash not match")
+	}
+	a, b := NewHasher[int](), NewHasher[int]()
+	if a(1) == b(1) && a(2) == b(2) {
+		t.Fatalf("hashers must use different seed")
+	}
+}
+
+func TestSharded(t *testing.T) {
+	var (
+		_ Cache[int, int] = lruCache[int, int]{}
+		_ Cache[int, int] = (*LRUWithLock[int, int])(nil)
+		_ Cache[int, int] = (*LFU[int, int])(nil)
+		_ Cache[int, int] = (*LFUWithLock[int, int])(nil)
+		_ Cache[int, int] = (*TinyLFU[int, int])(nil)
+		_ Cache[int, int] = (*Sharded[int, int])(nil)
+	)
+
+	policies := map[string]func() Cache[shardedKey, int]{
+		"lru": func() Cache[shardedKey, int] { return NewLRU[shardedKey, int](100).AsCache() },
+		"lfu": func() Cache[shardedKey, int] { return NewLFU[shardedKey, int](100) },
+		"tinylfu": func() Cache[shardedKey, int] {
+			return NewTinyLFUWithHasher[shardedKey, int](100, NewHasher[shardedKey]())
+		},
+	}
+	for name, newShard := range policies {
+		s := NewSharded(10, newShard)
+		if s.GetShardNum() != 16 {
+			t.Fatalf("%v shard num %v", name, s.GetShardNum())
+		}
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(g)))
+				for n := 0; n < 5000; n++ {
+					k := shardedKey{id: r.Int63n(1000), name: "k"}
+					switch r.Intn(4) {
+					case 0:
+						s.Set(k, int(k.id))
+					case 1:
+						s.Delete(k)
+					default:
+						if v, o := s.Get(k); o && v != int(k.id) {
+							t.Errorf("%v get %v got %v", name, k, v)
+							return
+						}
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		if s.Len() > 16*100 {
+			t.Fatalf("%v length %v", name, s.Len())
+		}
+		k := shardedKey{id: 5000, name: "x", tag: 1}
+		s.Set(k, 1)
+		if !s.Has(k) || s.Has(shardedKey{id: 5000, name: "x"}) {
+			t.Fatalf("%v has not match", name)
+		}
+		s.Clear()
+		if s.Len() != 0 {
+			t.Fatalf("%v length after clear %v", name, s.Len())
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package cache
+
+const (
+	sketchDepth      = 4
+	sketchMaxCounter = 15
+)
+
+// countMinSketch 估计访问频次，每个计数器最大为15，
+// 增加的次数达到sampleSize时所有计数器减半，使过去的热点逐渐冷却
+type countMinSketch struct {
+	counters   []uint8
+	mask       uint64
+	additions  int32
+	sampleSize int32
+}
+
+// newCountMinSketch 每行的宽度为不小于4倍容量的2的幂，减少冲突
+func newCountMinSketch(cap int32) *countMinSketch {
+	width := uint64(16)
+	for width < 4*uint64(cap) {
+		width <<= 1
+	}
+	return &countMinSketch{
+		counters:   make([]uint8, sketchDepth*width),
+		mask:       width - 1,
+		sampleSize: 10 * cap,
+	}
+}
+
+// index 第i行的计数器下标，用双重哈希得到各行的位置
+func (s *countMinSketch) index(h uint64, i int) uint64 {
+	h1, h2 := h, (h>>32)|1
+	return uint64(i)*(s.mask+1) + ((h1 + uint64(i)*h2) & s.mask)
+}
+
+func (s *countMinSketch) increment(h uint64) {
+	added := false
+	for i := 0; i < sketchDepth; i++ {
+		idx := s.index(h, i)
+		if s.counters[idx] < sketchMaxCounter {
+			s.counters[idx]++
+			added = true
+		}
+	}
+	if added {
+		s.additions++
+		if s.additions >= s.sampleSize {
+			s.reset()
+		}
+	}
+}
+
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	min := uint8(sketchMaxCounter)
+	for i := 0; i < sketchDepth; i++ {
+		if c := s.counters[s.index(h, i)]; c < min {
+			min = c
+		}
+	}
+	return min
+}
+
+// reset 所有计数器减半
+func (s *countMinSketch) reset() {
+	for i := range s.counters {
+		s.counters[i] >>= 1
+	}
+	s.additions /= 2
+}
+
+func (s *countMinSketch) clear() {
+	for i := range s.counters {
+		s.counters[i] = 0
+	}
+	s.additions = 0
+}
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats 缓存统计的快照
+type Stats struct {
+	Hits       int64         `json:"hits"`
+	Misses     int64         `json:"misses"`
+	Evictions  int64         `json:"evictions"` // 因超出容量或者过期被移除的个数
+	Loads      int64         `json:"loads"`
+	LoadErrors int64         `json:"load_errors"`
+	LoadTime   time.Duration `json:"load_time"` // 加载的总耗时
+}
+
+// HitRate 命中率，没有访问时为0
+func (s Stats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// AverageLoadTime ...
+func (s Stats) AverageLoadTime() time.Duration {
+	if s.Loads == 0 {
+		return 0
+	}
+	return s.LoadTime / time.Duration(s.Loads)
+}
+
+// Add 合并两个统计
+func (s Stats) Add(o Stats) Stats {
+	return Stats{
+		Hits:       s.Hits + o.Hits,
+		Misses:     s.Misses + o.Misses,
+		Evictions:  s.Evictions + o.Evictions,
+		Loads:      s.Loads + o.Loads,
+		LoadErrors: s.LoadErrors + o.LoadErrors,
+		LoadTime:   s.LoadTime + o.LoadTime,
+	}
+}
+
+// StatsRecorder 统计记录器，可能被多个goroutine同时调用，实现需要是线程安全的
+type StatsRecorder interface {
+	RecordHit()
+	RecordMiss()
+	RecordEviction()
+	RecordLoad(cost time.Duration, err error)
+	Snapshot() Stats
+}
+
+// AtomicStatsRecorder 用原子操作计数的默认统计记录器
+type AtomicStatsRecorder struct {
+	hits, misses, evictions, loads, loadErrors, loadTime atomic.Int64
+}
+
+// NewAtomicStatsRecorder ...
+func NewAtomicStatsRecorder() *AtomicStatsRecorder {
+	return &AtomicStatsRecorder{}
+}
+
+func (r *AtomicStatsRecorder) RecordHit() {
+	r.hits.Add(1)
+}
+
+func (r *AtomicStatsRecorder) RecordMiss() {
+	r.misses.Add(1)
+}
+
+func (r *AtomicStatsRecorder) RecordEviction() {
+	r.evictions.Add(1)
+}
+
+func (r *AtomicStatsRecorder) RecordLoad(cost time.Duration, err error) {
+	r.loads.Add(1)
+	r.loadTime.Add(int64(cost))
+	if err != nil {
+		r.loadErrors.Add(1)
+	}
+}
+
+func (r *AtomicStatsRecorder) Snapshot() Stats {
+	return Stats{
+		Hits:       r.hits.Load(),
+		Misses:     r.misses.Load(),
+		Evictions:  r.evictions.Load(),
+		Loads:      r.loads.Load(),
+		LoadErrors: r.loadErrors.Load(),
+		LoadTime:   time.Duration(r.loadTime.Load()),
+	}
+}
+
+// statsBase 嵌入到各个缓存中，没有设置记录器时不统计
+type statsBase struct {
+	recorder StatsRecorder
+}
+
+// WithStats 使用默认的统计记录器
+func (s *statsBase) WithStats() {
+	s.recorder = NewAtomicStatsRecorder()
+}
+
+// WithStatsRecorder 使用自定义的统计记录器
+func (s *statsBase) WithStatsRecorder(recorder StatsRecorder) {
+	s.recorder = recorder
+}
+
+// Stats 统计快照，没有开启统计时为零值
+func (s *statsBase) Stats() Stats {
+	if s.recorder == nil {
+		return Stats{}
+	}
+	return s.recorder.Snapshot()
+}
+
+func (s *statsBase) recordGet(hit bool) {
+	if s.recorder == nil {
+		return
+	}
+	if hit {
+		s.recorder.RecordHit()
+	} else {
+		s.recorder.RecordMiss()
+	}
+}
+
+func (s *statsBase) recordEviction() {
+	if s.recorder != nil {
+		s.recorder.RecordEviction()
+	}
+}
+
+func (s *statsBase) recordLoad(cost time.Duration, err error) {
+	if s.recorder != nil {
+		s.recorder.RecordLoad(cost, err)
+	}
+}
+
+// StatsProvider 提供统计快照的缓存
+type StatsProvider interface {
+	Stats() Stats
+}
+
+type statsJSON struct {
+	Stats
+	HitRate         float64       `json:"hit_rate"`
+	AverageLoadTime time.Duration `json:"average_load_time"`
+}
+
+// StatsHandler 以json输出注册的各个缓存的统计，可以挂载到http.Service上
+type StatsHandler struct {
+	locker    sync.RWMutex
+	providers map[string]StatsProvider
+}
+
+// NewStatsHandler ...
+func NewStatsHandler() *StatsHandler {
+	return &StatsHandler{
+		providers: make(map[string]StatsProvider),
+	}
+}
+
+// Register 注册名字为name的缓存
+func (h *StatsHandler) Register(name string, provider StatsProvider) {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+	h.providers[name] = provider
+}
+
+// Unregister ...
+func (h *StatsHandler) Unregister(name string) {
+	h.locker.Lock()
+	defer h.locker.Unlock()
+	delete(h.providers, name)
+}
+
+// ServeHTTP 可以用name参数只获取一个缓存的统计
+func (h *StatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.locker.RLock()
+	names := make([]string, 0, len(h.providers))
+	for name := range h.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if name := r.URL.Query().Get("name"); name != "" {
+		if _, o := h.providers[name]; !o {
+			h.locker.RUnlock()
+			http.NotFound(w, r)
+			return
+		}
+		names = []string{name}
+	}
+	result := make(map[string]statsJSON, len(names))
+	for _, name := range names {
+		s := h.providers[name].Stats()
+		result[name] = statsJSON{Stats: s, HitRate: s.HitRate(), AverageLoadTime: s.AverageLoadTime()}
+	}
+	h.locker.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	phttp "github.com/huoshan017/ponu/http"
+)
+
+func TestStats(t *testing.T) {
+	lru := NewLRU[int, int](2)
+	lru.WithStats()
+	lru.Set(1, 1)
+	lru.Set(2, 2)
+	lru.Get(1)
+	lru.Set(3, 3) // 淘汰2
+	lru.Get(2)
+	lru.SetExpired(4, 4, time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	lru.Get(4)
+	if s := lru.Stats(); s.Hits != 1 || s.Misses != 2 || s.Evictions != 3 || s.HitRate() != 1.0/3 {
+		t.Fatalf("lru stats %+v", s)
+	}
+
+	lfu := NewLFUWithLock[int, int](minCap)
+	lfu.WithStats()
+	for i := 0; i < minCap+2; i++ {
+		lfu.Set(i, i)
+		lfu.Get(i)
+	}
+	if s := lfu.Stats(); s.Hits != minCap+2 || s.Evictions != 2 {
+		t.Fatalf("lfu stats %+v", s)
+	}
+
+	c := NewConcurrentLFU[int32, int32](100)
+	c.WithStats()
+	for i := int32(0); i < 200; i++ {
+		c.Set(i, i)
+	}
+	for i := int32(0); i < 200; i++ {
+		c.Get(i)
+	}
+	if s := c.Stats(); s.Hits+s.Misses != 200 || s.Evictions != 100 || s.Hits != 100 {
+		t.Fatalf("concurrent lfu stats %+v", s)
+	}
+
+	loading := NewLoadingCache[int, int](NewLRUWithLock[int, *Loaded[int]](10))
+	loading.WithStats()
+	loading.GetOrLoad(1, func(k int) (int, error) { return k, nil })
+	loading.GetOrLoad(1, func(k int) (int, error) { return k, nil })
+	loading.GetOrLoad(2, func(k int) (int, error) { return 0, errors.New("bad") })
+	if s := loading.Stats(); s.Hits != 1 || s.Misses != 2 || s.Loads != 2 || s.LoadErrors != 1 {
+		t.Fatalf("loading cache stats %+v", s)
+	}
+
+	h := NewStatsHandler()
+	h.Register("lru", lru)
+	h.Register("concurrent_lfu", c)
+	var service phttp.Service
+	service.Init()
+	service.Handle("/cache/stats", h)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cache/stats", nil))
+	var result map[string]map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("unmarshal stats: %v", err)
+	}
+	if len(result) != 2 || result["lru"]["hits"] != float64(1) {
+		t.Fatalf("stats handler got %v", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/cache/stats?name=none", nil))
+	if w.Code != 404 {
+		t.Fatalf("stats of unknown name got code %v", w.Code)
+	}
+}
@@ -0,0 +1,283 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/huoshan017/ponu/list"
+)
+
+const (
+	segWindow    int8 = iota // 准入窗口
+	segProbation             // 主区域的试用段
+	segProtected             // 主区域的保护段
+)
+
+type tinyLFUNode[K comparable, V any] struct {
+	iter list.IteratorT[Pair[K, V]]
+	seg  int8
+}
+
+// TinyLFU Window-TinyLFU缓存
+// 新元素先进入占容量1%的LRU准入窗口，从窗口淘汰的元素和主区域将要淘汰的元素比较估计的访问频次，
+// 频次高的留在主区域。主区域是分段LRU，试用段中再次被访问的元素晋升到占主区域80%的保护段。
+// 访问频次由count-min sketch估计，定期减半，过去的热点会逐渐冷却
+type TinyLFU[K comparable, V any] struct {
+	cap          int32
+	windowCap    int32
+	protectedCap int32
+	hasher       func(K) uint64
+	sketch       *countMinSketch
+	onEvictFun   func(K, V, EvictReason)
+	segs         [3]list.ListT[Pair[K, V]]
+	m            map[K]tinyLFUNode[K, V]
+	pairPool     *list.ListTNodePool[Pair[K, V]]
+}
+
+// NewTinyLFU 整数和字符串类型的key使用默认的哈希函数
+func NewTinyLFU[K comparable, V any](cap int32) *TinyLFU[K, V] {
+	return NewTinyLFUWithHasher[K, V](cap, NewHasher[K]())
+}
+
+// NewTinyLFUWithHasher ...
+func NewTinyLFUWithHasher[K comparable, V any](cap int32, hasher func(K) uint64) *TinyLFU[K, V] {
+	if hasher == nil {
+		panic("ponu cache: TinyLFU need hasher for key type")
+	}
+	if cap < minCap {
+		cap = minCap
+	}
+	windowCap := cap / 100
+	if windowCap < 1 {
+		windowCap = 1
+	}
+	c := &TinyLFU[K, V]{
+		cap:          cap,
+		windowCap:    windowCap,
+		protectedCap: (cap - windowCap) * 8 / 10,
+		hasher:       hasher,
+		sketch:       newCountMinSketch(cap),
+		m:            make(map[K]tinyLFUNode[K, V]),
+		pairPool:     list.NewListTNodePool[Pair[K, V]](),
+	}
+	for i := range c.segs {
+		c.segs[i] = list.NewListTObjWithPool(c.pairPool)
+	}
+	return c
+}
+
+// WithOnEvict 元素因超出容量、删除、替换被移除时回调
+func (c *TinyLFU[K, V]) WithOnEvict(fun func(K, V, EvictReason)) {
+	c.onEvictFun = fun
+}
+
+func (c *TinyLFU[K, V]) Set(key K, value V) {
+	c.sketch.increment(c.hasher(key))
+	if n, o := c.m[key]; o {
+		old := n.iter.Value()
+		c.segs[n.seg].Update(Pair[K, V]{k: key, v: value}, n.iter)
+		c.onAccess(key, n)
+		if c.onEvictFun != nil {
+			c.onEvictFun(old.k, old.v, EvictReplaced)
+		}
+		return
+	}
+	c.pushBack(segWindow, Pair[K, V]{k: key, v: value})
+	for c.segs[segWindow].GetLength() > c.windowCap {
+		c.admit()
+	}
+}
+
+func (c *TinyLFU[K, V]) Get(key K) (V, bool) {
+	c.sketch.increment(c.hasher(key))
+	n, o := c.m[key]
+	if !o {
+		var v V
+		return v, false
+	}
+	v := n.iter.Value().v
+	c.onAccess(key, n)
+	return v, true
+}
+
+func (c *TinyLFU[K, V]) Has(key K) bool {
+	_, o := c.m[key]
+	return o
+}
+
+func (c *TinyLFU[K, V]) Delete(key K) bool {
+	n, o := c.m[key]
+	if !o {
+		return false
+	}
+	p := n.iter.Value()
+	c.segs[n.seg].Delete(n.iter)
+	delete(c.m, key)
+	if c.onEvictFun != nil {
+		c.onEvictFun(p.k, p.v, EvictDeleted)
+	}
+	return true
+}
+
+func (c *TinyLFU[K, V]) Len() int32 {
+	return int32(len(c.m))
+}
+
+// ToList 按窗口、试用段、保护段的顺序，每段从最久未访问的开始
+func (c *TinyLFU[K, V]) ToList(lis *list.ListT[Pair[K, V]]) {
+	for i := range c.segs {
+		for iter := c.segs[i].Begin(); iter != c.segs[i].End(); iter = iter.Next() {
+			lis.PushBack(iter.Value())
+		}
+	}
+}
+
+// Range 顺序和ToList相同
+func (c *TinyLFU[K, V]) Range(f func(key K, value V) bool) {
+	for i := range c.segs {
+		for iter := c.segs[i].Begin(); iter != c.segs[i].End(); iter = iter.Next() {
+			p := iter.Value()
+			if !f(p.k, p.v) {
+				return
+			}
+		}
+	}
+}
+
+func (c *TinyLFU[K, V]) Keys() []K {
+	return collectKeys(c.Len(), c.Range)
+}
+
+func (c *TinyLFU[K, V]) Clear() {
+	for i := range c.segs {
+		c.segs[i].Clear()
+	}
+	c.m = make(map[K]tinyLFUNode[K, V])
+	c.sketch.clear()
+}
+
+func (c *TinyLFU[K, V]) pushBack(seg int8, p Pair[K, V]) {
+	c.segs[seg].PushBack(p)
+	c.m[p.k] = tinyLFUNode[K, V]{iter: c.segs[seg].RBegin(), seg: seg}
+}
+
+func (c *TinyLFU[K, V]) popFront(seg int8) (Pair[K, V], bool) {
+	p, o := c.segs[seg].PopFront()
+	if o {
+		delete(c.m, p.k)
+	}
+	return p, o
+}
+
+// onAccess 窗口和保护段中的元素移到最近访问的位置，试用段中的元素晋升到保护段
+func (c *TinyLFU[K, V]) onAccess(key K, n tinyLFUNode[K, V]) {
+	p := n.iter.Value()
+	if n.seg != segProbation {
+		if n.iter != c.segs[n.seg].RBegin() {
+			c.segs[n.seg].Delete(n.iter)
+			c.pushBack(n.seg, p)
+		}
+		return
+	}
+	c.segs[segProbation].Delete(n.iter)
+	c.pushBack(segProtected, p)
+	// 保护段满了则把最久未访问的降级到试用段
+	for c.segs[segProtected].GetLength() > c.protectedCap {
+		demoted, _ := c.popFront(segProtected)
+		c.pushBack(segProbation, demoted)
+	}
+}
+
+// admit 窗口中最久未访问的元素作为候选者，主区域满时和主区域的淘汰者比较频次，淘汰频次低的
+func (c *TinyLFU[K, V]) admit() {
+	candidate, _ := c.popFront(segWindow)
+	mainLen := c.segs[segProbation].GetLength() + c.segs[segProtected].GetLength()
+	if mainLen < c.cap-c.windowCap {
+		c.pushBack(segProbation, candidate)
+		return
+	}
+	victimSeg := segProbation
+	if c.segs[segProbation].GetLength() == 0 {
+		victimSeg = segProtected
+	}
+	victim, _ := c.segs[victimSeg].Front()
+	if c.sketch.estimate(c.hasher(candidate.k)) > c.sketch.estimate(c.hasher(victim.k)) {
+		c.popFront(victimSeg)
+		c.pushBack(segProbation, candidate)
+		candidate = victim
+	}
+	if c.onEvictFun != nil {
+		c.onEvictFun(candidate.k, candidate.v, EvictCapacity)
+	}
+}
+
+type TinyLFUWithLock[K comparable, V any] struct {
+	*TinyLFU[K, V]
+	locker sync.Mutex
+}
+
+func NewTinyLFUWithLock[K comparable, V any](cap int32) *TinyLFUWithLock[K, V] {
+	return &TinyLFUWithLock[K, V]{
+		TinyLFU: NewTinyLFU[K, V](cap),
+	}
+}
+
+func (c *TinyLFUWithLock[K, V]) WithOnEvict(fun func(K, V, EvictReason)) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.TinyLFU.WithOnEvict(fun)
+}
+
+func (c *TinyLFUWithLock[K, V]) Set(key K, value V) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.TinyLFU.Set(key, value)
+}
+
+// Get 访问会更新频次和位置，所以要加写锁
+func (c *TinyLFUWithLock[K, V]) Get(key K) (V, bool) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.TinyLFU.Get(key)
+}
+
+func (c *TinyLFUWithLock[K, V]) Has(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.TinyLFU.Has(key)
+}
+
+func (c *TinyLFUWithLock[K, V]) Delete(key K) bool {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.TinyLFU.Delete(key)
+}
+
+func (c *TinyLFUWithLock[K, V]) Len() int32 {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.TinyLFU.Len()
+}
+
+func (c *TinyLFUWithLock[K, V]) ToList(lis *list.ListT[Pair[K, V]]) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.TinyLFU.ToList(lis)
+}
+
+func (c *TinyLFUWithLock[K, V]) Range(f func(key K, value V) bool) {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.TinyLFU.Range(f)
+}
+
+func (c *TinyLFUWithLock[K, V]) Keys() []K {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	return c.TinyLFU.Keys()
+}
+
+func (c *TinyLFUWithLock[K, V]) Clear() {
+	c.locker.Lock()
+	defer c.locker.Unlock()
+	c.TinyLFU.Clear()
+}
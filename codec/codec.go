@@ -0,0 +1,60 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+)
+
+// Codec 单个值的编解码，用于快照、持久化等场景
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// JSON 用encoding/json编解码
+type JSON[T any] struct{}
+
+// Marshal ...
+func (JSON[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal ...
+func (JSON[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// Binary 用encoding/binary按小端编解码，T必须是固定大小的类型
+type Binary[T any] struct{}
+
+// Marshal ...
+func (Binary[T]) Marshal(v T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal ...
+func (Binary[T]) Unmarshal(data []byte) (T, error) {
+	var v T
+	err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &v)
+	return v, err
+}
+
+// String 字符串直接作为字节
+type String struct{}
+
+// Marshal ...
+func (String) Marshal(v string) ([]byte, error) {
+	return []byte(v), nil
+}
+
+// Unmarshal ...
+func (String) Unmarshal(data []byte) (string, error) {
+	return string(data), nil
+}
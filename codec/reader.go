@@ -0,0 +1,66 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+)
+
+// ErrLength 读取到的长度不合法
+var ErrLength = errors.New("ponu codec: invalid length")
+
+// 长度来自不可信的数据，超过chunkSize时分块读取，内存随实际读到的数据增长
+const chunkSize = 64 * 1024
+
+// Reader 读取varint和带长度前缀的字节数组，用于快照等场景
+type Reader struct {
+	br   io.ByteReader
+	r    io.Reader
+	data []byte
+}
+
+// NewReader r没有实现io.ByteReader时加上缓冲
+func NewReader(r io.Reader) *Reader {
+	br, o := r.(io.ByteReader)
+	if !o {
+		b := bufio.NewReader(r)
+		br, r = b, b
+	}
+	return &Reader{br: br, r: r}
+}
+
+// ReadVarint 数据不完整时返回io.ErrUnexpectedEOF
+func (r *Reader) ReadVarint() (int64, error) {
+	v, err := binary.ReadVarint(r.br)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+// ReadBytes 读取varint长度和对应的字节，长度为负时返回ErrLength
+// 返回的切片在下次调用时被覆盖
+func (r *Reader) ReadBytes() ([]byte, error) {
+	n, err := r.ReadVarint()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, ErrLength
+	}
+	r.data = r.data[:0]
+	for int64(len(r.data)) < n {
+		l := len(r.data)
+		m := int(min(n-int64(l), chunkSize))
+		r.data = slices.Grow(r.data, m)[:l+m]
+		if _, err = io.ReadFull(r.r, r.data[l:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return r.data, nil
+}
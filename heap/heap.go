@@ -0,0 +1,190 @@
+package heap
+
+// Handle 元素在堆中的句柄，由Push返回，元素被弹出或者删除后失效
+type Handle[T any] struct {
+	h     *Heap[T]
+	value T
+	index int // 在堆数组中的位置，-1表示已不在堆中
+}
+
+// Value ...
+func (e *Handle[T]) Value() T {
+	return e.value
+}
+
+// Valid 元素是否还在堆中
+func (e *Handle[T]) Valid() bool {
+	return e.index >= 0
+}
+
+// Update 更新元素的值并调整位置，元素不在堆中返回false
+func (e *Handle[T]) Update(v T) bool {
+	if e.index < 0 {
+		return false
+	}
+	e.value = v
+	e.h.fix(e.index)
+	return true
+}
+
+// Fix 元素的值在外部被修改(比如T是指针)之后调整位置
+func (e *Handle[T]) Fix() bool {
+	if e.index < 0 {
+		return false
+	}
+	e.h.fix(e.index)
+	return true
+}
+
+// Remove 从堆中删除元素
+func (e *Handle[T]) Remove() bool {
+	if e.index < 0 {
+		return false
+	}
+	e.h.remove(e.index)
+	return true
+}
+
+// Heap 使用比较函数的二叉堆，可以通过Push返回的句柄更新或者删除元素
+type Heap[T any] struct {
+	array []*Handle[T]
+	less  func(a, b T) bool
+	t     HeapType
+}
+
+// NewHeap less比较元素的大小，HeapType_Min时最小的在堆顶，HeapType_Max时最大的在堆顶
+func NewHeap[T any](less func(a, b T) bool, t HeapType) *Heap[T] {
+	if t != HeapType_Max && t != HeapType_Min {
+		panic("ponu: heap type invalid")
+	}
+	if less == nil {
+		panic("ponu: heap need less function")
+	}
+	return &Heap[T]{
+		less: less,
+		t:    t,
+	}
+}
+
+func NewMaxHeap[T any](less func(a, b T) bool) *Heap[T] {
+	return NewHeap(less, HeapType_Max)
+}
+
+func NewMinHeap[T any](less func(a, b T) bool) *Heap[T] {
+	return NewHeap(less, HeapType_Min)
+}
+
+// Push 插入元素，返回的句柄在元素弹出或删除之前有效
+func (h *Heap[T]) Push(v T) *Handle[T] {
+	e := &Handle[T]{h: h, value: v, index: len(h.array)}
+	h.array = append(h.array, e)
+	h.up(e.index)
+	return e
+}
+
+// Pop 弹出堆顶的元素
+func (h *Heap[T]) Pop() (T, bool) {
+	if len(h.array) <= 0 {
+		var v T
+		return v, false
+	}
+	v := h.array[0].value
+	h.remove(0)
+	return v, true
+}
+
+// Peek 获取堆顶的元素
+func (h *Heap[T]) Peek() (T, bool) {
+	if len(h.array) <= 0 {
+		var v T
+		return v, false
+	}
+	return h.array[0].value, true
+}
+
+// PeekHandle 获取堆顶元素的句柄，堆为空时返回nil
+func (h *Heap[T]) PeekHandle() *Handle[T] {
+	if len(h.array) <= 0 {
+		return nil
+	}
+	return h.array[0]
+}
+
+func (h *Heap[T]) Length() int32 {
+	return int32(len(h.array))
+}
+
+// Clear 清空，所有句柄失效
+func (h *Heap[T]) Clear() {
+	for i, e := range h.array {
+		e.index = -1
+		h.array[i] = nil
+	}
+	h.array = h.array[:0]
+}
+
+// front a是否应该在b的上面
+func (h *Heap[T]) front(a, b T) bool {
+	if h.t == HeapType_Max {
+		return h.less(b, a)
+	}
+	return h.less(a, b)
+}
+
+func (h *Heap[T]) swap(i, j int) {
+	h.array[i], h.array[j] = h.array[j], h.array[i]
+	h.array[i].index = i
+	h.array[j].index = j
+}
+
+func (h *Heap[T]) up(n int) bool {
+	moved := false
+	for n > 0 {
+		p := (n - 1) / 2
+		if !h.front(h.array[n].value, h.array[p].value) {
+			break
+		}
+		h.swap(n, p)
+		n = p
+		moved = true
+	}
+	return moved
+}
+
+func (h *Heap[T]) down(c int) {
+	l := len(h.array)
+	for {
+		m := c*2 + 1 // left child
+		if m >= l {
+			break
+		}
+		if r := m + 1; r < l && h.front(h.array[r].value, h.array[m].value) {
+			m = r
+		}
+		if !h.front(h.array[m].value, h.array[c].value) {
+			break
+		}
+		h.swap(c, m)
+		c = m
+	}
+}
+
+func (h *Heap[T]) fix(n int) {
+	if !h.up(n) {
+		h.down(n)
+	}
+}
+
+func (h *Heap[T]) remove(n int) {
+	e := h.array[n]
+	last := len(h.array) - 1
+	if n != last {
+		h.swap(n, last)
+	}
+	h.array[last] = nil
+	h.array = h.array[:last]
+	e.index = -1
+	if n != last {
+		h.fix(n)
+	}
+}
@@ -0,0 +1,156 @@
+package heap
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+type task struct {
+	id       int
+	priority int
+}
+
+func TestHeapHandle(t *testing.T) {
+	var (
+		h       = NewMinHeap(func(a, b task) bool { return a.priority < b.priority })
+		r       = rand.New(rand.NewSource(time.Now().UnixNano()))
+		handles = make(map[int]*Handle[task])
+	)
+	for i := 0; i < 1000; i++ {
+		handles[i] = h.Push(task{id: i, priority: r.Intn(10000)})
+	}
+	for i := 0; i < 1000; i += 3 {
+		e := handles[i]
+		if !e.Update(task{id: i, priority: r.Intn(10000)}) || e.Value().id != i {
+			t.Fatalf("update %v failed", i)
+		}
+	}
+	for i := 1; i < 1000; i += 5 {
+		if !handles[i].Remove() || handles[i].Valid() || handles[i].Remove() {
+			t.Fatalf("remove %v failed", i)
+		}
+		delete(handles, i)
+	}
+
+	var expect []int
+	for _, e := range handles {
+		expect = append(expect, e.Value().priority)
+	}
+	sort.Ints(expect)
+	if h.Length() != int32(len(expect)) {
+		t.Fatalf("length %v, expect %v", h.Length(), len(expect))
+	}
+	for i, p := range expect {
+		top := h.PeekHandle()
+		v, o := h.Pop()
+		if !o || v.priority != p || top.Valid() || top.Update(v) {
+			t.Fatalf("pop %v got %v, expect %v", i, v.priority, p)
+		}
+	}
+	if _, o := h.Pop(); o {
+		t.Fatalf("pop empty heap")
+	}
+}
+
+func TestHeapFix(t *testing.T) {
+	h := NewMaxHeap(func(a, b *task) bool { return a.priority < b.priority })
+	tasks := make([]*task, 10)
+	handles := make([]*Handle[*task], 10)
+	for i := range tasks {
+		tasks[i] = &task{id: i, priority: i}
+		handles[i] = h.Push(tasks[i])
+	}
+	tasks[2].priority = 100
+	handles[2].Fix()
+	tasks[9].priority = -1
+	handles[9].Fix()
+	if v, _ := h.Peek(); v.id != 2 {
+		t.Fatalf("peek got %v", v.id)
+	}
+	expect := []int{2, 8, 7, 6, 5, 4, 3, 1, 0, 9}
+	for _, id := range expect {
+		if v, _ := h.Pop(); v.id != id {
+			t.Fatalf("pop got %v, expect %v", v.id, id)
+		}
+	}
+	h.Push(&task{})
+	e := h.Push(&task{})
+	h.Clear()
+	if h.Length() != 0 || e.Valid() {
+		t.Fatalf("clear failed")
+	}
+}
+
+// TestHeapDijkstra 网格上的最短路径，用Update降低距离
+func TestHeapDijkstra(t *testing.T) {
+	const size = 30
+	type node struct {
+		pos  int
+		dist int
+	}
+	var (
+		r       = rand.New(rand.NewSource(1))
+		cost    [size * size]int
+		dist    [size * size]int
+		handles [size * size]*Handle[node]
+		h       = NewMinHeap(func(a, b node) bool { return a.dist < b.dist })
+	)
+	for i := range cost {
+		cost[i] = r.Intn(9) + 1
+		dist[i] = -1
+	}
+	dist[0] = 0
+	handles[0] = h.Push(node{0, 0})
+	for h.Length() > 0 {
+		n, _ := h.Pop()
+		x, y := n.pos%size, n.pos/size
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			nx, ny := x+d[0], y+d[1]
+			if nx < 0 || ny < 0 || nx >= size || ny >= size {
+				continue
+			}
+			p := ny*size + nx
+			nd := n.dist + cost[p]
+			if dist[p] >= 0 && dist[p] <= nd {
+				continue
+			}
+			dist[p] = nd
+			if handles[p] != nil && handles[p].Valid() {
+				handles[p].Update(node{p, nd})
+			} else {
+				handles[p] = h.Push(node{p, nd})
+			}
+		}
+	}
+
+	// 用Bellman-Ford松弛验证
+	var check [size * size]int
+	for i := range check {
+		check[i] = 1 << 30
+	}
+	check[0] = 0
+	for changed := true; changed; {
+		changed = false
+		for p := range check {
+			x, y := p%size, p/size
+			for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+				nx, ny := x+d[0], y+d[1]
+				if nx < 0 || ny < 0 || nx >= size || ny >= size {
+					continue
+				}
+				q := ny*size + nx
+				if check[p]+cost[q] < check[q] {
+					check[q] = check[p] + cost[q]
+					changed = true
+				}
+			}
+		}
+	}
+	for i := range dist {
+		if dist[i] != check[i] {
+			t.Fatalf("dist of %v got %v, expect %v", i, dist[i], check[i])
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package rankinglist
+
+import (
+	"container/heap"
+)
+
+// RankingGroup 一组使用相同排序规则的排行榜(比如全服榜、区服榜、好友榜)，
+// 一次分数更新通过membership分发到key所属的各个排行榜
+type RankingGroup[K comparable, S any] struct {
+	boards     map[string]*RankingList[K, S]
+	keyBoards  map[K][]string
+	membership func(K) []string
+	less       func(S, S) bool
+	keyLess    func(K, K) bool
+	order      Order
+	tieBreak   TieBreak
+	serial     int64 // 所有排行榜共享，跨排行榜比较时先达到的排在前面
+}
+
+// NewRankingGroup membership返回key所属的排行榜名字
+func NewRankingGroup[K comparable, S any](less func(S, S) bool, order Order, membership func(K) []string) *RankingGroup[K, S] {
+	return newRankingGroup[K, S](less, nil, order, TieBreakFirstAchieved, membership)
+}
+
+// NewRankingGroupWithKeyTieBreak ...
+func NewRankingGroupWithKeyTieBreak[K comparable, S any](less func(S, S) bool, keyLess func(K, K) bool, order Order, membership func(K) []string) *RankingGroup[K, S] {
+	if keyLess == nil {
+		panic("ponu.rankinglist: NewRankingGroupWithKeyTieBreak need key less function")
+	}
+	return newRankingGroup(less, keyLess, order, TieBreakKey, membership)
+}
+
+func newRankingGroup[K comparable, S any](less func(S, S) bool, keyLess func(K, K) bool, order Order, tieBreak TieBreak, membership func(K) []string) *RankingGroup[K, S] {
+	if less == nil || membership == nil {
+		panic("ponu.rankinglist: NewRankingGroup need score less function and membership function")
+	}
+	return &RankingGroup[K, S]{
+		boards:     make(map[string]*RankingList[K, S]),
+		keyBoards:  make(map[K][]string),
+		membership: membership,
+		less:       less,
+		keyLess:    keyLess,
+		order:      order,
+		tieBreak:   tieBreak,
+	}
+}
+
+// AddBoard 添加排行榜，已存在则返回已有的
+func (g *RankingGroup[K, S]) AddBoard(name string, maxLength int) *RankingList[K, S] {
+	if board, o := g.boards[name]; o {
+		return board
+	}
+	board := newRankingList(g.less, g.keyLess, g.order, g.tieBreak, maxLength)
+	board._serial = &g.serial
+	g.boards[name] = board
+	return board
+}
+
+// GetBoard ...
+func (g *RankingGroup[K, S]) GetBoard(name string) *RankingList[K, S] {
+	return g.boards[name]
+}
+
+// InsertOrUpdate 更新key在所属的各个排行榜中的分数，并从不再属于的排行榜中删除
+func (g *RankingGroup[K, S]) InsertOrUpdate(key K, score S) {
+	names := g.membership(key)
+	for _, old := range g.keyBoards[key] {
+		if !containsName(names, old) {
+			if board := g.boards[old]; board != nil {
+				board.Delete(key)
+			}
+		}
+	}
+	for _, name := range names {
+		if board := g.boards[name]; board != nil {
+			board.InsertOrUpdate(key, score)
+		}
+	}
+	if len(names) > 0 {
+		g.keyBoards[key] = names
+	} else {
+		delete(g.keyBoards, key)
+	}
+}
+
+// Delete 从所有排行榜中删除key
+func (g *RankingGroup[K, S]) Delete(key K) {
+	for _, name := range g.keyBoards[key] {
+		if board := g.boards[name]; board != nil {
+			board.Delete(key)
+		}
+	}
+	delete(g.keyBoards, key)
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+type mergeCursor[K comparable, S any] struct {
+	board *RankingList[K, S]
+	rank  int32
+	it    item[K, S]
+}
+
+type mergeHeap[K comparable, S any] struct {
+	cursors []*mergeCursor[K, S]
+	front   func(a, b item[K, S]) bool
+}
+
+func (h *mergeHeap[K, S]) Len() int { return len(h.cursors) }
+func (h *mergeHeap[K, S]) Less(i, j int) bool {
+	return h.front(h.cursors[i].it, h.cursors[j].it)
+}
+func (h *mergeHeap[K, S]) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+func (h *mergeHeap[K, S]) Push(x any)    { h.cursors = append(h.cursors, x.(*mergeCursor[K, S])) }
+func (h *mergeHeap[K, S]) Pop() any {
+	n := len(h.cursors)
+	c := h.cursors[n-1]
+	h.cursors = h.cursors[:n-1]
+	return c
+}
+
+// MergedTop 多路归并shards的前n名，shards中的key应该互不重复
+func (g *RankingGroup[K, S]) MergedTop(n int32, shards ...string) []RankItem[K, S] {
+	if n <= 0 {
+		return nil
+	}
+	h := &mergeHeap[K, S]{}
+	for _, name := range shards {
+		board := g.boards[name]
+		if board == nil {
+			continue
+		}
+		if h.front == nil {
+			h.front = board.front
+		}
+		if it, o := board.getItemByRank(1); o {
+			h.cursors = append(h.cursors, &mergeCursor[K, S]{board: board, rank: 1, it: it})
+		}
+	}
+	heap.Init(h)
+
+	var result []RankItem[K, S]
+	for h.Len() > 0 && int32(len(result)) < n {
+		c := h.cursors[0]
+		result = append(result, RankItem[K, S]{key: c.it.key, score: c.it.score, rank: int32(len(result) + 1)})
+		c.rank++
+		if it, o := c.board.getItemByRank(c.rank); o {
+			c.it = it
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return result
+}
+
+// GlobalRank 不归并而是由key在各个shard中的排名相加得到在shards中的总排名，不存在返回0
+func (g *RankingGroup[K, S]) GlobalRank(key K, shards ...string) int32 {
+	var (
+		it    item[K, S]
+		found bool
+	)
+	for _, name := range shards {
+		if board := g.boards[name]; board != nil {
+			if it, found = board._key2Value[key]; found {
+				break
+			}
+		}
+	}
+	if !found {
+		return 0
+	}
+	rank := int32(1)
+	for _, name := range shards {
+		if board := g.boards[name]; board != nil {
+			rank += board.countFront(it)
+		}
+	}
+	return rank
+}
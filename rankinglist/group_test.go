@@ -0,0 +1,74 @@
+package rankinglist
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func Test_group(t *testing.T) {
+	region := func(key int) string {
+		return []string{"east", "west", "north"}[key%3]
+	}
+	// 玩家所在区服可能改变
+	regions := make(map[int]string)
+	g := NewRankingGroup[int, int](func(a, b int) bool { return a < b }, OrderDesc, func(key int) []string {
+		return []string{"global", regions[key]}
+	})
+	for _, name := range []string{"global", "east", "west", "north"} {
+		g.AddBoard(name, 0)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	scores := make(map[int]int)
+	for i := 0; i < 3000; i++ {
+		key := r.Intn(300)
+		if r.Intn(20) == 0 {
+			regions[key] = region(key + 1)
+		} else if _, o := regions[key]; !o {
+			regions[key] = region(key)
+		}
+		scores[key] = r.Intn(100)
+		g.InsertOrUpdate(key, scores[key])
+	}
+	g.Delete(7)
+	delete(scores, 7)
+
+	global := g.GetBoard("global")
+	if global.GetLength() != int32(len(scores)) {
+		t.Fatalf("global length %v, expect %v", global.GetLength(), len(scores))
+	}
+	var sum int32
+	for _, name := range []string{"east", "west", "north"} {
+		sum += g.GetBoard(name).GetLength()
+	}
+	if sum != global.GetLength() {
+		t.Fatalf("sum of regional length %v not equal to global %v", sum, global.GetLength())
+	}
+
+	shards := []string{"east", "west", "north"}
+	merged := g.MergedTop(50, shards...)
+	expect := global.GetByRankRange(1, 50)
+	if len(merged) != len(expect) {
+		t.Fatalf("merged length %v", len(merged))
+	}
+	for i := range merged {
+		if merged[i] != expect[i] {
+			t.Fatalf("merged %v got %+v, expect %+v", i, merged[i], expect[i])
+		}
+	}
+
+	keys := make([]int, 0, len(scores))
+	for k := range scores {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		if rank := g.GlobalRank(k, shards...); rank != global.GetRank(k) {
+			t.Fatalf("global rank of %v got %v, expect %v", k, rank, global.GetRank(k))
+		}
+	}
+	if g.GlobalRank(7, shards...) != 0 {
+		t.Fatalf("global rank of deleted key must be 0")
+	}
+}
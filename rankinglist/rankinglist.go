@@ -1,186 +1,311 @@
 package rankinglist
 
 import (
-	"reflect"
-
 	"github.com/huoshan017/ponu/skiplist"
 )
 
+// Order 排序方式
+type Order int8
+
+const (
+	OrderDesc Order = iota // 降序，分数大的排在前面
+	OrderAsc               // 升序，分数小的排在前面
+)
+
+// TieBreak 分数相同时的排序方式
+type TieBreak int8
+
+const (
+	TieBreakFirstAchieved TieBreak = iota // 先达到该分数的排在前面
+	TieBreakKey                           // 按key排序
+)
+
+type item[K comparable, S any] struct {
+	key    K
+	score  S
+	serial int64 // 达到当前分数的序号
+}
+
 // RankItem ...
-type RankItem interface {
-	skiplist.SkiplistNode
-	InitKeyValues(interface{}, ...interface{})
-	GetKey() interface{}
-	GetValues() []interface{}
-}
-
-// RankingList ...
-type RankingList struct {
-	_list        *skiplist.Skiplist
-	_key2Value   map[interface{}]RankItem
-	_maxLength   int
-	_type        reflect.Type
-	_rankItemObj RankItem
-}
-
-// NewRankingList ...
-func NewRankingList(item RankItem, maxLength int) *RankingList {
-	typ := reflect.TypeOf(item)
-	rankingList := &RankingList{
-		_list:      skiplist.NewSkiplist(),
-		_key2Value: make(map[interface{}]RankItem),
+type RankItem[K comparable, S any] struct {
+	key   K
+	score S
+	rank  int32
+}
+
+// GetKey ...
+func (i RankItem[K, S]) GetKey() K {
+	return i.key
+}
+
+// GetScore ...
+func (i RankItem[K, S]) GetScore() S {
+	return i.score
+}
+
+// GetRank ...
+func (i RankItem[K, S]) GetRank() int32 {
+	return i.rank
+}
+
+// RankingList 排行榜，排名从1开始
+type RankingList[K comparable, S any] struct {
+	_list      *skiplist.SkiplistT[K, item[K, S]]
+	_key2Value map[K]item[K, S]
+	_maxLength int
+	_less      func(S, S) bool
+	_keyLess   func(K, K) bool
+	_order     Order
+	_tieBreak  TieBreak
+	_serial    *int64 // 可以和同一个RankingGroup的其他排行榜共享
+	_onEvict   func(K, S)
+}
+
+// NewRankingList 创建排行榜，less比较分数的大小，分数相同时先达到的排在前面，maxLength小于等于0表示不限长度
+func NewRankingList[K comparable, S any](less func(S, S) bool, order Order, maxLength int) *RankingList[K, S] {
+	return newRankingList[K, S](less, nil, order, TieBreakFirstAchieved, maxLength)
+}
+
+// NewRankingListWithKeyTieBreak 创建排行榜，分数相同时按keyLess排序
+func NewRankingListWithKeyTieBreak[K comparable, S any](less func(S, S) bool, keyLess func(K, K) bool, order Order, maxLength int) *RankingList[K, S] {
+	if keyLess == nil {
+		panic("ponu.rankinglist: NewRankingListWithKeyTieBreak need key less function")
+	}
+	return newRankingList(less, keyLess, order, TieBreakKey, maxLength)
+}
+
+func newRankingList[K comparable, S any](less func(S, S) bool, keyLess func(K, K) bool, order Order, tieBreak TieBreak, maxLength int) *RankingList[K, S] {
+	if less == nil {
+		panic("ponu.rankinglist: NewRankingList need score less function")
+	}
+	r := &RankingList[K, S]{
+		_key2Value: make(map[K]item[K, S]),
 		_maxLength: maxLength,
-		_type:      typ,
+		_less:      less,
+		_keyLess:   keyLess,
+		_order:     order,
+		_tieBreak:  tieBreak,
+		_serial:    new(int64),
+	}
+	r._list = skiplist.NewSkiplistT[K, item[K, S]](r.front, func(a, b K) bool { return a == b })
+	return r
+}
+
+// WithOnEvict 设置淘汰回调，排行榜已满或者缩小最大长度时被挤出的元素会回调
+func (r *RankingList[K, S]) WithOnEvict(fun func(key K, score S)) *RankingList[K, S] {
+	r._onEvict = fun
+	return r
+}
+
+// SetMaxLength 设置最大长度，小于等于0表示不限长度，超出的排名靠后的元素被淘汰
+func (r *RankingList[K, S]) SetMaxLength(maxLength int) {
+	r._maxLength = maxLength
+	if maxLength <= 0 {
+		return
 	}
-	v := reflect.New(typ.Elem())
-	rankItem := (v.Interface()).(RankItem)
-	if rankItem == nil {
-		panic("NewRankingList: reflect value %v cant be convert to RankItem interface type")
+	for len(r._key2Value) > maxLength {
+		r.evictTail()
 	}
-	rankingList._rankItemObj = rankItem
-	return rankingList
 }
 
-func (r *RankingList) insert(key interface{}, values ...interface{}) bool {
-	v := reflect.New(r._type.Elem())
-	var rankItem RankItem = (v.Interface()).(RankItem)
-	rankItem.InitKeyValues(key, values)
-	r._list.Insert(rankItem)
-	r._key2Value[key] = rankItem
-	return true
+// GetMaxLength ...
+func (r *RankingList[K, S]) GetMaxLength() int {
+	return r._maxLength
 }
 
-// Insert ...
-func (r *RankingList) Insert(key interface{}, values ...interface{}) bool {
-	_, o := r._key2Value[key]
+func (r *RankingList[K, S]) evictTail() {
+	key, it, o := r._list.DeleteTail()
 	if !o {
+		return
+	}
+	delete(r._key2Value, key)
+	if r._onEvict != nil {
+		r._onEvict(key, it.score)
+	}
+}
+
+// front a是否排在b的前面
+func (r *RankingList[K, S]) front(a, b item[K, S]) bool {
+	if r._order == OrderDesc {
+		if r._less(b.score, a.score) {
+			return true
+		}
+		if r._less(a.score, b.score) {
+			return false
+		}
+	} else {
+		if r._less(a.score, b.score) {
+			return true
+		}
+		if r._less(b.score, a.score) {
+			return false
+		}
+	}
+	if r._tieBreak == TieBreakKey {
+		return r._keyLess(a.key, b.key)
+	}
+	return a.serial < b.serial
+}
+
+func (r *RankingList[K, S]) insert(it item[K, S]) {
+	r._list.Insert(it.key, it)
+	r._key2Value[it.key] = it
+}
+
+func (r *RankingList[K, S]) newItem(key K, score S) item[K, S] {
+	*r._serial += 1
+	return item[K, S]{key: key, score: score, serial: *r._serial}
+}
+
+// Insert 插入新的key，key已存在或者排行榜已满且分数不够进入排行榜返回false
+func (r *RankingList[K, S]) Insert(key K, score S) bool {
+	if _, o := r._key2Value[key]; o {
 		return false
 	}
+	it := r.newItem(key, score)
 	// length is full
-	if r._list.GetLength() > 0 && r._maxLength > 0 && r._maxLength >= len(r._key2Value) {
-		tail, o := r._list.GetTail()
+	if r._maxLength > 0 && len(r._key2Value) >= r._maxLength {
+		_, tail, o := r._list.GetTail()
 		if !o {
 			return false
 		}
-		t := tail.(RankItem)
-		if t == nil {
-			return false
-		}
-		r._rankItemObj.InitKeyValues(key, values)
 		// tail value front to insert value
-		if t.FrontTo(r._rankItemObj) {
+		if !r.front(it, tail) {
 			return false
 		}
-		r._list.DeleteTail()
+		r.evictTail()
 	}
-	return r.insert(key, values)
+	r.insert(it)
+	return true
 }
 
-func (r *RankingList) delete(key interface{}, item RankItem) bool {
-	delete(r._key2Value, key)
-	return r._list.Delete(item)
+func (r *RankingList[K, S]) delete(it item[K, S]) bool {
+	delete(r._key2Value, it.key)
+	return r._list.Delete(it.key, it)
 }
 
 // Delete ...
-func (r *RankingList) Delete(key interface{}) bool {
-	item, o := r._key2Value[key]
+func (r *RankingList[K, S]) Delete(key K) bool {
+	it, o := r._key2Value[key]
 	if !o {
 		return false
 	}
-	return r.delete(key, item)
+	return r.delete(it)
 }
 
-func (r *RankingList) update(key interface{}, value interface{}, item RankItem) bool {
-	if !r.delete(key, item) {
-		return false
+func (r *RankingList[K, S]) update(it item[K, S], score S) {
+	r._list.Delete(it.key, it)
+	// 分数没变则保留原来的序号
+	if r._less(it.score, score) || r._less(score, it.score) {
+		it = r.newItem(it.key, score)
+	} else {
+		it.score = score
 	}
-	return r.insert(key, value)
+	r.insert(it)
 }
 
-// Update ...
-func (r *RankingList) Update(key interface{}, value interface{}) bool {
-	item, o := r._key2Value[key]
+// Update 更新已存在的key的分数
+func (r *RankingList[K, S]) Update(key K, score S) bool {
+	it, o := r._key2Value[key]
 	if !o {
 		return false
 	}
-	return r.update(key, value, item)
+	r.update(it, score)
+	return true
 }
 
 // InsertOrUpdate ...
-func (r *RankingList) InsertOrUpdate(key interface{}, value interface{}) bool {
-	var res bool
-	item, o := r._key2Value[key]
+func (r *RankingList[K, S]) InsertOrUpdate(key K, score S) bool {
+	it, o := r._key2Value[key]
 	if !o {
-		res = r.insert(key, value)
-	} else {
-		res = r.update(key, value, item)
+		return r.Insert(key, score)
 	}
-	return res
+	r.update(it, score)
+	return true
 }
 
-// GetValue ...
-func (r *RankingList) GetValue(key interface{}) ([]interface{}, bool) {
-	n, o := r._key2Value[key]
-	if !o {
-		return nil, false
-	}
-	v := n.GetValues()
-	return v, true
+// GetScore ...
+func (r *RankingList[K, S]) GetScore(key K) (S, bool) {
+	it, o := r._key2Value[key]
+	return it.score, o
 }
 
-// GetValueAndRank ...
-func (r *RankingList) GetValueAndRank(key interface{}) ([]interface{}, int32, bool) {
-	n, o := r._key2Value[key]
+// GetRank 获取排名，不存在返回0
+func (r *RankingList[K, S]) GetRank(key K) int32 {
+	it, o := r._key2Value[key]
 	if !o {
-		return nil, 0, false
+		return 0
 	}
-	value := n.GetValues()
-	rank := r._list.GetRank(n)
-	return value, rank, true
+	return r._list.GetRank(key, it)
 }
 
-// GetKeyValueByRank ...
-func (r *RankingList) GetKeyValueByRank(rank int32) (interface{}, []interface{}, bool) {
-	node := r._list.GetByRank(rank)
-	if node == nil {
-		return nil, nil, false
-	}
-	item := node.(RankItem)
-	if item == nil {
-		panic("RankingList.GetValueByRank: the node cant convert to RankItem interface type")
+// GetScoreAndRank ...
+func (r *RankingList[K, S]) GetScoreAndRank(key K) (S, int32, bool) {
+	it, o := r._key2Value[key]
+	if !o {
+		var s S
+		return s, 0, false
 	}
-	return item.GetKey(), item.GetValues(), true
+	return it.score, r._list.GetRank(key, it), true
 }
 
-// GetItem ...
-func (r *RankingList) GetItem(key interface{}) RankItem {
-	return r._key2Value[key]
+// GetByRank ...
+func (r *RankingList[K, S]) GetByRank(rank int32) (K, S, bool) {
+	key, it, o := r._list.GetByRank(rank)
+	return key, it.score, o
 }
 
 // HasKey ...
-func (r *RankingList) HasKey(key interface{}) bool {
+func (r *RankingList[K, S]) HasKey(key K) bool {
 	_, o := r._key2Value[key]
 	return o
 }
 
-// GetByRankRange ...
-func (r *RankingList) GetByRankRange(rankStart, rankNum int32) []interface{} {
-	var nodeList []skiplist.SkiplistNode = make([]skiplist.SkiplistNode, rankNum)
-	if !r._list.GetByRankRange(rankStart, rankNum, nodeList) {
+// GetLength ...
+func (r *RankingList[K, S]) GetLength() int32 {
+	return int32(len(r._key2Value))
+}
+
+// GetByRankRange 从排名rankStart开始获取最多rankNum个
+func (r *RankingList[K, S]) GetByRankRange(rankStart, rankNum int32) []RankItem[K, S] {
+	if rankNum <= 0 {
 		return nil
 	}
+	// 先截断到列表长度，避免rankStart+rankNum溢出int32
+	rankEnd := int32(min(int64(rankStart)+int64(rankNum)-1, int64(r.GetLength())))
+	var result []RankItem[K, S]
+	r._list.RangeByRank(rankStart, rankEnd, func(rank int32, key K, it item[K, S]) bool {
+		result = append(result, RankItem[K, S]{key: key, score: it.score, rank: rank})
+		return true
+	})
+	return result
+}
 
-	var result []interface{}
-	for rank := rankStart; rank < rankStart+rankNum; rank++ {
-		node := r._list.GetByRank(rank)
-		if node == nil {
-			break
-		}
-		rnode := node.(RankItem)
-		if rnode == nil {
-			panic("RankingList.GetByRankRange: cant convert node to RankItem interface type")
-		}
-		result = append(result, rnode.GetValues())
+func (r *RankingList[K, S]) getItemByRank(rank int32) (item[K, S], bool) {
+	_, it, o := r._list.GetByRank(rank)
+	return it, o
+}
+
+// countFront 排在it前面的元素个数
+func (r *RankingList[K, S]) countFront(it item[K, S]) int32 {
+	return r._list.CountLess(it)
+}
+
+// GetAround 获取key及其前面before个、后面after个元素，key不存在返回nil
+func (r *RankingList[K, S]) GetAround(key K, before, after int32) []RankItem[K, S] {
+	rank := r.GetRank(key)
+	if rank == 0 {
+		return nil
 	}
-	return result
+	if before < 0 {
+		before = 0
+	}
+	if after < 0 {
+		after = 0
+	}
+	start := rank - before
+	if start < 1 {
+		start = 1
+	}
+	return r.GetByRankRange(start, rank+after-start+1)
 }
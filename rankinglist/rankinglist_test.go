@@ -1,59 +1,126 @@
 package rankinglist
 
 import (
+	"math"
 	"testing"
-
-	"github.com/huoshan017/ponu/skiplist"
 )
 
-type TestType struct {
-	key      int32
-	level    int32
-	serialID int64
-}
-
-func (t *TestType) FrontTo(node skiplist.SkiplistNode) bool {
-	tt := node.(*TestType)
-	if t.level < tt.level {
-		return false
+func Test_one(t *testing.T) {
+	maxLength := 100000
+	rankingList := NewRankingList[int32, int32](func(a, b int32) bool { return a < b }, OrderDesc, maxLength)
+	for i := 0; i < maxLength; i++ {
+		if !rankingList.Insert(int32(i+1), int32(i+100)) {
+			t.Fatalf("insert key %v failed", i+1)
+		}
 	}
-	if t.level == tt.level {
-		if t.serialID >= tt.serialID {
-			return false
+	for key := int32(100); key <= int32(maxLength); key += 10000 {
+		score, rank, o := rankingList.GetScoreAndRank(key)
+		if !o || rank != int32(maxLength)-key+1 {
+			t.Fatalf("key %v score %v rank %v", key, score, rank)
 		}
+		t.Logf("key is %v, score is %v, rank is %v", key, score, rank)
 	}
-	return true
 }
 
-func (t *TestType) KeyEqualTo(node skiplist.SkiplistNode) bool {
-	tt := node.(*TestType)
-	return t.key == tt.key
-}
+func Test_tie_break(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
 
-func (t *TestType) InitKeyValues(key interface{}, values ...interface{}) {
-	t.key = key.(int32)
-	t.level = values[0].(int32)
-	t.serialID = values[1].(int64)
-}
+	r := NewRankingList[string, int](less, OrderDesc, 0)
+	r.Insert("a", 10)
+	r.Insert("b", 20)
+	r.Insert("c", 10)
+	r.Insert("d", 10)
+	// 同分数先达到的排在前面
+	r.Update("a", 5)
+	r.Update("a", 10)
+	items := r.GetByRankRange(1, 10)
+	expect := []string{"b", "c", "d", "a"}
+	if len(items) != len(expect) {
+		t.Fatalf("items length %v", len(items))
+	}
+	for i, it := range items {
+		if it.GetKey() != expect[i] || it.GetRank() != int32(i+1) {
+			t.Fatalf("rank %v got %v, expect %v", i+1, it.GetKey(), expect[i])
+		}
+	}
+	// rankNum很大时不会溢出
+	if items = r.GetByRankRange(2, math.MaxInt32); len(items) != 3 || items[0].GetKey() != "c" {
+		t.Fatalf("get by rank range with max rank num got %v items", len(items))
+	}
+	if items = r.GetByRankRange(math.MaxInt32, math.MaxInt32); len(items) != 0 {
+		t.Fatalf("get by rank range beyond length got %v items", len(items))
+	}
+	// 分数不变的更新不影响排名
+	r.Update("c", 10)
+	if r.GetRank("c") != 2 {
+		t.Fatalf("rank of c changed by updating same score")
+	}
 
-func (t *TestType) GetKey() interface{} {
-	return t.key
+	k := NewRankingListWithKeyTieBreak[string, int](less, func(a, b string) bool { return a < b }, OrderAsc, 0)
+	k.Insert("d", 1)
+	k.Insert("b", 1)
+	k.Insert("c", 0)
+	k.Insert("a", 2)
+	expect = []string{"c", "b", "d", "a"}
+	for i, e := range expect {
+		if key, _, _ := k.GetByRank(int32(i + 1)); key != e {
+			t.Fatalf("rank %v got %v, expect %v", i+1, key, e)
+		}
+	}
 }
 
-func (t *TestType) GetValues() []interface{} {
-	return []interface{}{t.level, t.serialID}
+func Test_max_length(t *testing.T) {
+	r := NewRankingList[int, int](func(a, b int) bool { return a < b }, OrderDesc, 3)
+	for i := 1; i <= 3; i++ {
+		r.Insert(i, i*10)
+	}
+	if r.Insert(4, 5) {
+		t.Fatalf("insert score lower than tail must fail when full")
+	}
+	if !r.Insert(5, 25) || r.GetLength() != 3 || r.HasKey(1) {
+		t.Fatalf("insert score higher than tail must replace tail")
+	}
+	if r.Insert(5, 100) || r.GetRank(5) != 2 {
+		t.Fatalf("insert existed key must fail")
+	}
+	if !r.InsertOrUpdate(5, 100) || r.GetRank(5) != 1 {
+		t.Fatalf("insert or update existed key failed")
+	}
+	if !r.Delete(5) || r.GetLength() != 2 {
+		t.Fatalf("delete failed")
+	}
 }
 
-func Test_one(t *testing.T) {
-	maxLength := 10000000
-	rankingList := NewRankingList(&TestType{}, maxLength)
-	for i := 0; i < maxLength; i++ {
-		rankingList.Insert((i + 1), i+100, i+1)
+func Test_evict_and_around(t *testing.T) {
+	var evicted []int
+	r := NewRankingList[int, int](func(a, b int) bool { return a < b }, OrderDesc, 5).WithOnEvict(func(key, score int) {
+		evicted = append(evicted, key)
+	})
+	for i := 1; i <= 8; i++ {
+		r.Insert(i, i)
 	}
-	for key := 100; key <= 10000000; key += 100 {
-		value, o := rankingList.GetValue(key)
-		if o {
-			t.Logf("key is %v, value is %v", key, value)
-		}
+	// 8 7 6 5 4
+	if r.GetLength() != 5 || len(evicted) != 3 || evicted[0] != 1 || evicted[2] != 3 {
+		t.Fatalf("evicted %v", evicted)
+	}
+
+	around := r.GetAround(6, 1, 2)
+	if len(around) != 4 || around[0].GetKey() != 7 || around[0].GetRank() != 2 || around[3].GetKey() != 4 {
+		t.Fatalf("around got %v", around)
+	}
+	if around = r.GetAround(8, 3, 1); len(around) != 2 || around[0].GetRank() != 1 {
+		t.Fatalf("around top got %v", around)
+	}
+	if around = r.GetAround(4, 1, 3); len(around) != 2 || around[1].GetKey() != 4 {
+		t.Fatalf("around tail got %v", around)
+	}
+	if r.GetAround(1, 1, 1) != nil {
+		t.Fatalf("around evicted key must be nil")
+	}
+
+	evicted = evicted[:0]
+	r.SetMaxLength(3)
+	if r.GetLength() != 3 || len(evicted) != 2 || evicted[0] != 4 || evicted[1] != 5 {
+		t.Fatalf("shrink evicted %v", evicted)
 	}
 }
@@ -0,0 +1,168 @@
+package rankinglist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/huoshan017/ponu/codec"
+	"github.com/huoshan017/ponu/skiplist"
+)
+
+const (
+	snapshotVersion  = 1
+	snapshotPrealloc = 4096
+)
+
+var (
+	ErrSnapshotVersion  = errors.New("ponu.rankinglist: snapshot version not supported")
+	ErrSnapshotMismatch = errors.New("ponu.rankinglist: snapshot order or tie break not match")
+	ErrSnapshotCorrupt  = errors.New("ponu.rankinglist: snapshot corrupt")
+)
+
+// 快照格式(整数都是varint)：
+// version order tieBreak serial count, 然后按排名依次是 keyLen key scoreLen score serial
+
+// Snapshot 按排名顺序把排行榜写入w，key和分数分别用keyCodec和scoreCodec编码
+func (r *RankingList[K, S]) Snapshot(w io.Writer, keyCodec codec.Codec[K], scoreCodec codec.Codec[S]) error {
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+	writeVarint := func(v int64) error {
+		_, err := bw.Write(buf[:binary.PutVarint(buf[:], v)])
+		return err
+	}
+	writeBytes := func(data []byte) error {
+		if err := writeVarint(int64(len(data))); err != nil {
+			return err
+		}
+		_, err := bw.Write(data)
+		return err
+	}
+
+	for _, v := range []int64{snapshotVersion, int64(r._order), int64(r._tieBreak), *r._serial, int64(r._list.GetLength())} {
+		if err := writeVarint(v); err != nil {
+			return err
+		}
+	}
+	var err error
+	r._list.RangeByRank(1, r._list.GetLength(), func(_ int32, key K, it item[K, S]) bool {
+		var data []byte
+		if data, err = keyCodec.Marshal(key); err != nil {
+			return false
+		}
+		if err = writeBytes(data); err != nil {
+			return false
+		}
+		if data, err = scoreCodec.Marshal(it.score); err != nil {
+			return false
+		}
+		if err = writeBytes(data); err != nil {
+			return false
+		}
+		err = writeVarint(it.serial)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Restore 用r中的快照替换排行榜的内容，快照已按排名排好序，线性时间重建跳表
+// 快照的长度超过最大长度时只保留前面的部分，后面的部分在恢复成功后从排名最后的开始回调淘汰回调
+func (r *RankingList[K, S]) Restore(rd io.Reader, keyCodec codec.Codec[K], scoreCodec codec.Codec[S]) error {
+	cr := codec.NewReader(rd)
+	readVarint := cr.ReadVarint
+	readBytes := func() ([]byte, error) {
+		b, err := cr.ReadBytes()
+		if err == codec.ErrLength {
+			err = ErrSnapshotCorrupt
+		}
+		return b, err
+	}
+
+	var header [5]int64
+	for i := range header {
+		v, err := readVarint()
+		if err != nil {
+			return err
+		}
+		header[i] = v
+	}
+	if header[0] != snapshotVersion {
+		return ErrSnapshotVersion
+	}
+	if Order(header[1]) != r._order || TieBreak(header[2]) != r._tieBreak {
+		return ErrSnapshotMismatch
+	}
+	serial, count := header[3], header[4]
+	if count < 0 {
+		return ErrSnapshotCorrupt
+	}
+	keep := count
+	if r._maxLength > 0 && keep > int64(r._maxLength) {
+		keep = int64(r._maxLength)
+	}
+
+	// count来自快照，预分配的大小有上限，超出的部分随读取增长
+	pairs := make([]skiplist.Pair[K, item[K, S]], 0, min(keep, snapshotPrealloc))
+	key2Value := make(map[K]item[K, S], min(keep, snapshotPrealloc))
+	var evicted []item[K, S]
+	for i := int64(0); i < count; i++ {
+		var it item[K, S]
+		b, err := readBytes()
+		if err != nil {
+			return err
+		}
+		if it.key, err = keyCodec.Unmarshal(b); err != nil {
+			return err
+		}
+		if b, err = readBytes(); err != nil {
+			return err
+		}
+		if it.score, err = scoreCodec.Unmarshal(b); err != nil {
+			return err
+		}
+		if it.serial, err = readVarint(); err != nil {
+			return err
+		}
+		if _, o := key2Value[it.key]; o {
+			return ErrSnapshotCorrupt
+		}
+		key2Value[it.key] = it
+		if i >= keep {
+			// 超出最大长度的元素也要检查重复，恢复成功后才回调
+			evicted = append(evicted, it)
+			continue
+		}
+		pairs = append(pairs, skiplist.NewPair(it.key, it))
+	}
+	if len(evicted) > 0 {
+		for _, e := range evicted {
+			delete(key2Value, e.key)
+		}
+		// 被丢弃的元素要排在保留的元素后面
+		if len(pairs) > 0 && r.front(evicted[0], pairs[len(pairs)-1].GetValue()) {
+			return ErrSnapshotCorrupt
+		}
+		for i := 1; i < len(evicted); i++ {
+			if r.front(evicted[i], evicted[i-1]) {
+				return ErrSnapshotCorrupt
+			}
+		}
+	}
+	if !r._list.LoadSorted(pairs) {
+		return ErrSnapshotCorrupt
+	}
+	r._key2Value = key2Value
+	if serial > *r._serial {
+		*r._serial = serial
+	}
+	if r._onEvict != nil {
+		for i := len(evicted) - 1; i >= 0; i-- {
+			r._onEvict(evicted[i].key, evicted[i].score)
+		}
+	}
+	return nil
+}
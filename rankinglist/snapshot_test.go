@@ -0,0 +1,108 @@
+package rankinglist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/huoshan017/ponu/codec"
+)
+
+func Test_snapshot(t *testing.T) {
+	less := func(a, b float64) bool { return a < b }
+	r := NewRankingList[string, float64](less, OrderDesc, 0)
+	keys := []string{"a", "b", "c", "d", "e", "f", "g"}
+	for i, k := range keys {
+		r.Insert(k, float64(i%3))
+	}
+	r.Update("a", 2)
+
+	for _, c := range []struct {
+		name  string
+		key   codec.Codec[string]
+		score codec.Codec[float64]
+	}{
+		{"binary", codec.String{}, codec.Binary[float64]{}},
+		{"json", codec.JSON[string]{}, codec.JSON[float64]{}},
+	} {
+		var buf bytes.Buffer
+		if err := r.Snapshot(&buf, c.key, c.score); err != nil {
+			t.Fatalf("%v snapshot: %v", c.name, err)
+		}
+		n := NewRankingList[string, float64](less, OrderDesc, 0)
+		n.Insert("x", 100)
+		if err := n.Restore(&buf, c.key, c.score); err != nil {
+			t.Fatalf("%v restore: %v", c.name, err)
+		}
+		if n.GetLength() != r.GetLength() || n.HasKey("x") {
+			t.Fatalf("%v restore length %v", c.name, n.GetLength())
+		}
+		for rank := int32(1); rank <= r.GetLength(); rank++ {
+			k1, s1, _ := r.GetByRank(rank)
+			k2, s2, _ := n.GetByRank(rank)
+			if k1 != k2 || s1 != s2 || n.GetRank(k2) != rank {
+				t.Fatalf("%v rank %v got %v:%v, expect %v:%v", c.name, rank, k2, s2, k1, s1)
+			}
+		}
+		// 恢复后先达到分数的顺序保持不变
+		r.Update("c", 1)
+		n.Update("c", 1)
+		if r.GetRank("c") != n.GetRank("c") {
+			t.Fatalf("%v tie break changed after restore", c.name)
+		}
+	}
+
+	var buf bytes.Buffer
+	r.Snapshot(&buf, codec.String{}, codec.Binary[float64]{})
+	data := buf.Bytes()
+
+	// 超过最大长度的部分被淘汰，从排名最后的开始回调
+	var evicted []string
+	short := NewRankingList[string, float64](less, OrderDesc, 4).
+		WithOnEvict(func(key string, score float64) { evicted = append(evicted, key) })
+	if err := short.Restore(bytes.NewReader(data), codec.String{}, codec.Binary[float64]{}); err != nil {
+		t.Fatalf("restore into short list: %v", err)
+	}
+	if short.GetLength() != 4 || len(evicted) != 3 {
+		t.Fatalf("short list length %v, evicted %v", short.GetLength(), evicted)
+	}
+	for i, k := range evicted {
+		if short.HasKey(k) || r.GetRank(k) != r.GetLength()-int32(i) {
+			t.Fatalf("evicted %v, expect from the last rank", evicted)
+		}
+	}
+
+	asc := NewRankingList[string, float64](less, OrderAsc, 0)
+	if err := asc.Restore(bytes.NewReader(data), codec.String{}, codec.Binary[float64]{}); err != ErrSnapshotMismatch {
+		t.Fatalf("restore with different order got %v", err)
+	}
+}
+
+func Test_snapshotCorrupt(t *testing.T) {
+	less := func(a, b float64) bool { return a < b }
+	header := func(count int64) []byte {
+		var b []byte
+		for _, v := range []int64{snapshotVersion, int64(OrderDesc), int64(TieBreakFirstAchieved), 0, count} {
+			b = binary.AppendVarint(b, v)
+		}
+		return b
+	}
+	for _, c := range []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"huge count", header(math.MaxInt64), io.ErrUnexpectedEOF},
+		{"negative count", header(-1), ErrSnapshotCorrupt},
+		{"huge key length", binary.AppendVarint(header(1), math.MaxInt64), io.ErrUnexpectedEOF},
+		{"negative key length", binary.AppendVarint(header(1), -5), ErrSnapshotCorrupt},
+		{"truncated key", append(binary.AppendVarint(header(1), 1<<20), "abc"...), io.ErrUnexpectedEOF},
+	} {
+		r := NewRankingList[string, float64](less, OrderDesc, 0)
+		if err := r.Restore(bytes.NewReader(c.data), codec.String{}, codec.Binary[float64]{}); err != c.err {
+			t.Fatalf("%v restore got %v, expect %v", c.name, err, c.err)
+		}
+	}
+}
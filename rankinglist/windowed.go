@@ -0,0 +1,217 @@
+package rankinglist
+
+import (
+	"time"
+
+	ptime "github.com/huoshan017/ponu/time"
+)
+
+// Scheduler 见time.Scheduler，定时器回调需要和排行榜的读写在同一个协程中执行
+type Scheduler = ptime.Scheduler
+
+// 剩余时间小于时间轮的间隔时，逐步加倍尝试的最长定时器
+const maxProbeTimeout = time.Minute
+
+// Daily 每天loc时区的零点滚动
+func Daily(loc *time.Location) func(time.Time) time.Time {
+	return func(t time.Time) time.Time {
+		t = t.In(loc)
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+	}
+}
+
+// Weekly 每周loc时区的weekday零点滚动
+func Weekly(weekday time.Weekday, loc *time.Location) func(time.Time) time.Time {
+	return func(t time.Time) time.Time {
+		t = t.In(loc)
+		days := (int(weekday) - int(t.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, loc)
+	}
+}
+
+// Window 一个时间窗口的排行榜
+type Window[K comparable, S any] struct {
+	list  *RankingList[K, S]
+	start time.Time
+	end   time.Time
+}
+
+// GetList ...
+func (w *Window[K, S]) GetList() *RankingList[K, S] {
+	return w.list
+}
+
+// GetStart ...
+func (w *Window[K, S]) GetStart() time.Time {
+	return w.start
+}
+
+// GetEnd ...
+func (w *Window[K, S]) GetEnd() time.Time {
+	return w.end
+}
+
+// WindowedRankingList 按时间窗口滚动的排行榜(日榜、周榜、赛季榜)，保留当前窗口和最近archiveNum个已结束的窗口
+// 非线程安全，滚动由Scheduler的定时器回调驱动
+type WindowedRankingList[K comparable, S any] struct {
+	current    *Window[K, S]
+	archives   []*Window[K, S] // 最近结束的在前面
+	archiveNum int
+	newList    func() *RankingList[K, S]
+	next       func(time.Time) time.Time
+	scheduler  Scheduler
+	timerId    uint32
+	running    bool
+	onRollover func(closed *Window[K, S])
+	onDrop     func(dropped *Window[K, S])
+}
+
+// NewWindowedRankingList newList创建每个窗口的排行榜，next返回给定时间之后的下一个滚动时间
+func NewWindowedRankingList[K comparable, S any](newList func() *RankingList[K, S], next func(time.Time) time.Time, archiveNum int) *WindowedRankingList[K, S] {
+	if newList == nil || next == nil {
+		panic("ponu.rankinglist: NewWindowedRankingList need new list function and next function")
+	}
+	if archiveNum < 0 {
+		archiveNum = 0
+	}
+	now := time.Now()
+	return &WindowedRankingList[K, S]{
+		current:    &Window[K, S]{list: newList(), start: now, end: next(now)},
+		archiveNum: archiveNum,
+		newList:    newList,
+		next:       next,
+	}
+}
+
+// WithOnRollover 窗口结束时回调，用于归档或者给前几名发奖
+func (w *WindowedRankingList[K, S]) WithOnRollover(fun func(closed *Window[K, S])) *WindowedRankingList[K, S] {
+	w.onRollover = fun
+	return w
+}
+
+// WithOnDrop 已结束的窗口超出保留个数被丢弃时回调
+func (w *WindowedRankingList[K, S]) WithOnDrop(fun func(dropped *Window[K, S])) *WindowedRankingList[K, S] {
+	w.onDrop = fun
+	return w
+}
+
+// Start 用scheduler在窗口结束时自动滚动，已经启动时先停止之前的定时器
+// scheduler不能添加定时器时(比如时间轮没有启动)返回false，不会自动滚动
+func (w *WindowedRankingList[K, S]) Start(scheduler Scheduler) bool {
+	w.Stop()
+	w.scheduler = scheduler
+	w.running = true
+	return w.schedule()
+}
+
+// Stop 停止自动滚动，取消已添加的定时器，scheduler不支持取消时定时器到期后不再处理
+func (w *WindowedRankingList[K, S]) Stop() {
+	w.running = false
+	w.cancelTimer()
+}
+
+func (w *WindowedRankingList[K, S]) cancelTimer() {
+	if w.timerId != 0 {
+		ptime.CancelTimer(w.scheduler, w.timerId)
+		w.timerId = 0
+	}
+}
+
+// schedule 窗口结束之前不滚动，定时器提前到期时在onTimer中重新计算
+// 添加不了定时器时停止自动滚动，返回false
+func (w *WindowedRankingList[K, S]) schedule() bool {
+	remain := time.Until(w.current.end)
+	if remain <= 0 {
+		w.Rollover(time.Now())
+		return w.running
+	}
+	// 超过时间轮最大时长时先用较短的定时器
+	for timeout := remain; timeout > 0; timeout /= 2 {
+		if w.timerId = w.scheduler.Add(timeout, w.onTimer, nil); w.timerId != 0 {
+			return true
+		}
+	}
+	// 剩余时间小于时间轮的间隔，用能添加的最短的定时器
+	for timeout := remain * 2; timeout <= maxProbeTimeout; timeout *= 2 {
+		if w.timerId = w.scheduler.Add(timeout, w.onTimer, nil); w.timerId != 0 {
+			return true
+		}
+	}
+	w.running = false
+	return false
+}
+
+func (w *WindowedRankingList[K, S]) onTimer(id uint32, args []any) {
+	if !w.running || id != w.timerId {
+		return
+	}
+	w.timerId = 0
+	if time.Now().Before(w.current.end) {
+		w.schedule()
+		return
+	}
+	w.Rollover(time.Now())
+}
+
+// Rollover 结束当前窗口并开始新的窗口，now之前错过的窗口都是空的，不保留
+func (w *WindowedRankingList[K, S]) Rollover(now time.Time) {
+	closed := w.current
+	if closed.end.After(now) {
+		closed.end = now
+	}
+	start := closed.end
+	end := w.next(start)
+	for !end.After(now) {
+		start, end = end, w.next(end)
+	}
+	w.current = &Window[K, S]{list: w.newList(), start: start, end: end}
+
+	if w.onRollover != nil {
+		w.onRollover(closed)
+	}
+	w.archives = append([]*Window[K, S]{closed}, w.archives...)
+	for len(w.archives) > w.archiveNum {
+		dropped := w.archives[len(w.archives)-1]
+		w.archives[len(w.archives)-1] = nil
+		w.archives = w.archives[:len(w.archives)-1]
+		if w.onDrop != nil {
+			w.onDrop(dropped)
+		}
+	}
+
+	if w.running {
+		w.cancelTimer()
+		w.schedule()
+	}
+}
+
+// Current 当前窗口
+func (w *WindowedRankingList[K, S]) Current() *Window[K, S] {
+	return w.current
+}
+
+// GetList 当前窗口的排行榜
+func (w *WindowedRankingList[K, S]) GetList() *RankingList[K, S] {
+	return w.current.list
+}
+
+// Archived 已结束的窗口，0是最近结束的
+func (w *WindowedRankingList[K, S]) Archived(i int) *Window[K, S] {
+	if i < 0 || i >= len(w.archives) {
+		return nil
+	}
+	return w.archives[i]
+}
+
+// ArchivedNum ...
+func (w *WindowedRankingList[K, S]) ArchivedNum() int {
+	return len(w.archives)
+}
+
+// InsertOrUpdate 更新当前窗口的分数
+func (w *WindowedRankingList[K, S]) InsertOrUpdate(key K, score S) bool {
+	return w.current.list.InsertOrUpdate(key, score)
+}
@@ -0,0 +1,140 @@
+package rankinglist
+
+import (
+	"testing"
+	"time"
+
+	ptime "github.com/huoshan017/ponu/time"
+)
+
+func Test_windowed(t *testing.T) {
+	const period = 100 * time.Millisecond
+	w := ptime.NewSWheel(time.Second, ptime.WithInterval(10*time.Millisecond))
+	w.Start()
+
+	var (
+		closed  []*Window[int, int]
+		dropped int
+	)
+	wl := NewWindowedRankingList(func() *RankingList[int, int] {
+		return NewRankingList[int, int](func(a, b int) bool { return a < b }, OrderDesc, 10)
+	}, func(t time.Time) time.Time {
+		return t.Truncate(period).Add(period)
+	}, 2).WithOnRollover(func(c *Window[int, int]) {
+		closed = append(closed, c)
+	}).WithOnDrop(func(*Window[int, int]) {
+		dropped++
+	})
+	if !wl.Start(w) {
+		t.Fatalf("start failed")
+	}
+
+	deadline := time.Now().Add(5*period + period/2)
+	for i := 0; time.Now().Before(deadline); i++ {
+		w.Update()
+		wl.InsertOrUpdate(i%20, i)
+		time.Sleep(time.Millisecond)
+	}
+	wl.Stop()
+
+	if len(closed) < 4 {
+		t.Fatalf("rollover %v times", len(closed))
+	}
+	if wl.ArchivedNum() != 2 || dropped != len(closed)-2 {
+		t.Fatalf("archived %v, dropped %v", wl.ArchivedNum(), dropped)
+	}
+	if wl.Archived(0) != closed[len(closed)-1] || wl.Archived(1) != closed[len(closed)-2] {
+		t.Fatalf("archived order not match")
+	}
+	for i := 1; i < len(closed); i++ {
+		c := closed[i]
+		if !c.GetStart().Equal(closed[i-1].GetEnd()) || c.GetEnd().Sub(c.GetStart()) != period {
+			t.Fatalf("window %v from %v to %v", i, c.GetStart(), c.GetEnd())
+		}
+		if c.GetList().GetLength() == 0 || c.GetList().GetLength() > 10 {
+			t.Fatalf("window %v length %v", i, c.GetList().GetLength())
+		}
+	}
+	if !wl.Current().GetStart().Equal(closed[len(closed)-1].GetEnd()) {
+		t.Fatalf("current window not follow the last closed window")
+	}
+}
+
+// fakeScheduler 和时间轮一样拒绝小于interval的定时器，到期的定时器由fire执行
+type fakeScheduler struct {
+	interval time.Duration
+	nextId   uint32
+	timers   map[uint32]ptime.TimerFunc
+}
+
+func (s *fakeScheduler) Add(timeout time.Duration, fun ptime.TimerFunc, args []any) uint32 {
+	if timeout < s.interval {
+		return 0
+	}
+	s.nextId++
+	s.timers[s.nextId] = fun
+	return s.nextId
+}
+
+func (s *fakeScheduler) Cancel(id uint32) bool {
+	_, o := s.timers[id]
+	delete(s.timers, id)
+	return o
+}
+
+func (s *fakeScheduler) fire() {
+	timers := s.timers
+	s.timers = make(map[uint32]ptime.TimerFunc)
+	for id, fun := range timers {
+		fun(id, nil)
+	}
+}
+
+func Test_windowedNearBoundary(t *testing.T) {
+	const period = time.Hour
+	var (
+		s      = &fakeScheduler{interval: 100 * time.Millisecond, timers: make(map[uint32]ptime.TimerFunc)}
+		end    = time.Now().Add(20 * time.Millisecond)
+		closed []*Window[int, int]
+	)
+	wl := NewWindowedRankingList(func() *RankingList[int, int] {
+		return NewRankingList[int, int](func(a, b int) bool { return a < b }, OrderDesc, 10)
+	}, func(t time.Time) time.Time {
+		e := end
+		for !e.After(t) {
+			e = e.Add(period)
+		}
+		return e
+	}, 2).WithOnRollover(func(c *Window[int, int]) {
+		closed = append(closed, c)
+	})
+	wl.InsertOrUpdate(1, 100)
+	// 剩余时间小于时间轮的间隔时不提前滚动
+	if !wl.Start(s) {
+		t.Fatalf("start failed near boundary")
+	}
+	if len(closed) != 0 || len(s.timers) != 1 || !wl.Current().GetEnd().Equal(end) {
+		t.Fatalf("rollover %v times, timers %v before window end", len(closed), len(s.timers))
+	}
+	time.Sleep(time.Until(end))
+	s.fire()
+	if len(closed) != 1 || !closed[0].GetEnd().Equal(end) || closed[0].GetList().GetLength() != 1 {
+		t.Fatalf("rollover %v times after window end", len(closed))
+	}
+	if wl.Archived(0) != closed[0] || !wl.Current().GetEnd().Equal(end.Add(period)) {
+		t.Fatalf("archived window not match")
+	}
+	// Stop取消定时器
+	if len(s.timers) != 1 {
+		t.Fatalf("timers %v for next window", len(s.timers))
+	}
+	wl.Stop()
+	if len(s.timers) != 0 {
+		t.Fatalf("timer not canceled after stop")
+	}
+
+	// 添加不了定时器时Start返回false
+	if wl.Start(&fakeScheduler{interval: 100 * time.Hour, timers: make(map[uint32]ptime.TimerFunc)}) || wl.running {
+		t.Fatalf("start must fail when no timer can be added")
+	}
+}
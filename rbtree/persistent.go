@@ -0,0 +1,326 @@
+package rbtree
+
+import (
+	"sync/atomic"
+
+	"golang.org/x/exp/constraints"
+)
+
+// 每次修改操作分配一个新版本号，只有本次操作创建的节点可以原地修改，其他节点都要先复制
+var persistentVersion uint64
+
+type pnode[K, V any] struct {
+	key     K
+	value   V
+	color   uint8
+	left    *pnode[K, V]
+	right   *pnode[K, V]
+	version uint64
+}
+
+func isRedP[K, V any](n *pnode[K, V]) bool {
+	return n != nil && n.color == NODE_COLOR_RED
+}
+
+func flipColor(color uint8) uint8 {
+	if color == NODE_COLOR_RED {
+		return NODE_COLOR_BLACK
+	}
+	return NODE_COLOR_RED
+}
+
+// pedit 一次修改操作的上下文
+type pedit[K, V any] struct {
+	less    func(K, K) bool
+	version uint64
+}
+
+func (e *pedit[K, V]) mut(n *pnode[K, V]) *pnode[K, V] {
+	if n.version == e.version {
+		return n
+	}
+	c := *n
+	c.version = e.version
+	return &c
+}
+
+func (e *pedit[K, V]) rotateLeft(h *pnode[K, V]) *pnode[K, V] {
+	x := e.mut(h.right)
+	h.right = x.left
+	x.left = h
+	x.color = h.color
+	h.color = NODE_COLOR_RED
+	return x
+}
+
+func (e *pedit[K, V]) rotateRight(h *pnode[K, V]) *pnode[K, V] {
+	x := e.mut(h.left)
+	h.left = x.right
+	x.right = h
+	x.color = h.color
+	h.color = NODE_COLOR_RED
+	return x
+}
+
+func (e *pedit[K, V]) flipColors(h *pnode[K, V]) {
+	h.color = flipColor(h.color)
+	h.left = e.mut(h.left)
+	h.left.color = flipColor(h.left.color)
+	h.right = e.mut(h.right)
+	h.right.color = flipColor(h.right.color)
+}
+
+func (e *pedit[K, V]) balance(h *pnode[K, V]) *pnode[K, V] {
+	if isRedP(h.right) && !isRedP(h.left) {
+		h = e.rotateLeft(h)
+	}
+	if isRedP(h.left) && isRedP(h.left.left) {
+		h = e.rotateRight(h)
+	}
+	if isRedP(h.left) && isRedP(h.right) {
+		e.flipColors(h)
+	}
+	return h
+}
+
+func (e *pedit[K, V]) insert(h *pnode[K, V], key K, value V) (*pnode[K, V], bool) {
+	if h == nil {
+		return &pnode[K, V]{key: key, value: value, color: NODE_COLOR_RED, version: e.version}, true
+	}
+	var added bool
+	h = e.mut(h)
+	if e.less(key, h.key) {
+		h.left, added = e.insert(h.left, key, value)
+	} else if e.less(h.key, key) {
+		h.right, added = e.insert(h.right, key, value)
+	} else {
+		h.value = value
+		return h, false
+	}
+	return e.balance(h), added
+}
+
+func (e *pedit[K, V]) moveRedLeft(h *pnode[K, V]) *pnode[K, V] {
+	e.flipColors(h)
+	if isRedP(h.right.left) {
+		h.right = e.rotateRight(h.right)
+		h = e.rotateLeft(h)
+		e.flipColors(h)
+	}
+	return h
+}
+
+func (e *pedit[K, V]) moveRedRight(h *pnode[K, V]) *pnode[K, V] {
+	e.flipColors(h)
+	if isRedP(h.left.left) {
+		h = e.rotateRight(h)
+		e.flipColors(h)
+	}
+	return h
+}
+
+func (e *pedit[K, V]) deleteMin(h *pnode[K, V]) *pnode[K, V] {
+	if h.left == nil {
+		return nil
+	}
+	h = e.mut(h)
+	if !isRedP(h.left) && !isRedP(h.left.left) {
+		h = e.moveRedLeft(h)
+	}
+	h.left = e.deleteMin(h.left)
+	return e.balance(h)
+}
+
+// delete 删除key，调用前要保证key存在
+func (e *pedit[K, V]) delete(h *pnode[K, V], key K) *pnode[K, V] {
+	h = e.mut(h)
+	if e.less(key, h.key) {
+		if !isRedP(h.left) && !isRedP(h.left.left) {
+			h = e.moveRedLeft(h)
+		}
+		h.left = e.delete(h.left, key)
+	} else {
+		if isRedP(h.left) {
+			h = e.rotateRight(h)
+		}
+		if !e.less(h.key, key) && h.right == nil {
+			return nil
+		}
+		if !isRedP(h.right) && !isRedP(h.right.left) {
+			h = e.moveRedRight(h)
+		}
+		if !e.less(h.key, key) {
+			// 用右子树的最小节点替换
+			m := h.right
+			for m.left != nil {
+				m = m.left
+			}
+			h.key, h.value = m.key, m.value
+			h.right = e.deleteMin(h.right)
+		} else {
+			h.right = e.delete(h.right, key)
+		}
+	}
+	return e.balance(h)
+}
+
+// PersistentRBTree 持久化(不可变)红黑树，Insert和Delete返回新的树，新树和旧树共享未修改的节点
+// 树本身就是一个O(1)的快照，可以被多个goroutine无锁读取
+type PersistentRBTree[K, V any] struct {
+	root   *pnode[K, V]
+	length int32
+	less   func(K, K) bool
+}
+
+func NewPersistentRBTree[K constraints.Ordered, V any]() *PersistentRBTree[K, V] {
+	return &PersistentRBTree[K, V]{
+		less: func(a, b K) bool { return a < b },
+	}
+}
+
+func NewPersistentRBTreeWithLess[K, V any](less func(K, K) bool) *PersistentRBTree[K, V] {
+	if less == nil {
+		panic("ponu.rbtree: PersistentRBTree need less function")
+	}
+	return &PersistentRBTree[K, V]{
+		less: less,
+	}
+}
+
+func (t *PersistentRBTree[K, V]) newEdit() pedit[K, V] {
+	return pedit[K, V]{
+		less:    t.less,
+		version: atomic.AddUint64(&persistentVersion, 1),
+	}
+}
+
+func (t *PersistentRBTree[K, V]) Len() int32 {
+	return t.length
+}
+
+// Insert 插入或替换，返回新的树
+func (t *PersistentRBTree[K, V]) Insert(key K, value V) *PersistentRBTree[K, V] {
+	e := t.newEdit()
+	root, added := e.insert(t.root, key, value)
+	root.color = NODE_COLOR_BLACK
+	nt := &PersistentRBTree[K, V]{
+		root:   root,
+		length: t.length,
+		less:   t.less,
+	}
+	if added {
+		nt.length += 1
+	}
+	return nt
+}
+
+// Delete 删除key，返回新的树，key不存在则返回原来的树和false
+func (t *PersistentRBTree[K, V]) Delete(key K) (*PersistentRBTree[K, V], bool) {
+	if !t.Has(key) {
+		return t, false
+	}
+	e := t.newEdit()
+	root := e.mut(t.root)
+	if !isRedP(root.left) && !isRedP(root.right) {
+		root.color = NODE_COLOR_RED
+	}
+	root = e.delete(root, key)
+	if root != nil {
+		root.color = NODE_COLOR_BLACK
+	}
+	return &PersistentRBTree[K, V]{
+		root:   root,
+		length: t.length - 1,
+		less:   t.less,
+	}, true
+}
+
+func (t *PersistentRBTree[K, V]) find(key K) *pnode[K, V] {
+	n := t.root
+	for n != nil {
+		if t.less(key, n.key) {
+			n = n.left
+		} else if t.less(n.key, key) {
+			n = n.right
+		} else {
+			return n
+		}
+	}
+	return nil
+}
+
+func (t *PersistentRBTree[K, V]) Get(key K) (V, bool) {
+	n := t.find(key)
+	if n == nil {
+		var v V
+		return v, false
+	}
+	return n.value, true
+}
+
+func (t *PersistentRBTree[K, V]) Has(key K) bool {
+	return t.find(key) != nil
+}
+
+func (t *PersistentRBTree[K, V]) Min() (K, V, bool) {
+	n := t.root
+	if n == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	for n.left != nil {
+		n = n.left
+	}
+	return n.key, n.value, true
+}
+
+func (t *PersistentRBTree[K, V]) Max() (K, V, bool) {
+	n := t.root
+	if n == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	for n.right != nil {
+		n = n.right
+	}
+	return n.key, n.value, true
+}
+
+// Each 按key从小到大遍历，f返回false则停止
+func (t *PersistentRBTree[K, V]) Each(f func(key K, value V) bool) {
+	t.each(t.root, f)
+}
+
+func (t *PersistentRBTree[K, V]) each(n *pnode[K, V], f func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	return t.each(n.left, f) && f(n.key, n.value) && t.each(n.right, f)
+}
+
+// Range 按key从小到大遍历[from, to]区间，f返回false则停止
+func (t *PersistentRBTree[K, V]) Range(from, to K, f func(key K, value V) bool) {
+	t.rangeFrom(t.root, from, to, f)
+}
+
+func (t *PersistentRBTree[K, V]) rangeFrom(n *pnode[K, V], from, to K, f func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	if t.less(from, n.key) && !t.rangeFrom(n.left, from, to, f) {
+		return false
+	}
+	if !t.less(n.key, from) && !t.less(to, n.key) && !f(n.key, n.value) {
+		return false
+	}
+	if t.less(n.key, to) {
+		return t.rangeFrom(n.right, from, to, f)
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package rbtree
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// checkPersistent 校验左倾红黑树性质，返回黑高
+func checkPersistent[K, V any](t *testing.T, tree *PersistentRBTree[K, V], n *pnode[K, V]) int {
+	if n == nil {
+		return 1
+	}
+	if n.left != nil && !tree.less(n.left.key, n.key) {
+		t.Fatalf("left child key %v not less than %v", n.left.key, n.key)
+	}
+	if n.right != nil && !tree.less(n.key, n.right.key) {
+		t.Fatalf("right child key %v not greater than %v", n.right.key, n.key)
+	}
+	if isRedP(n.right) {
+		t.Fatalf("node %v has red right child", n.key)
+	}
+	if isRedP(n) && isRedP(n.left) {
+		t.Fatalf("red node %v has red child", n.key)
+	}
+	lh := checkPersistent(t, tree, n.left)
+	if rh := checkPersistent(t, tree, n.right); lh != rh {
+		t.Fatalf("black height of node %v not balanced: %v != %v", n.key, lh, rh)
+	}
+	if !isRedP(n) {
+		lh += 1
+	}
+	return lh
+}
+
+func persistentKeys(tree *PersistentRBTree[int, int]) []int {
+	var keys []int
+	tree.Each(func(k, _ int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+func TestPersistentRBTree(t *testing.T) {
+	var (
+		tree      = NewPersistentRBTree[int, int]()
+		m         = make(map[int]int)
+		r         = rand.New(rand.NewSource(time.Now().Unix()))
+		snapshots []*PersistentRBTree[int, int]
+		expects   [][]int
+	)
+	for i := 0; i < 20000; i++ {
+		k := r.Intn(3000)
+		if r.Intn(3) == 0 {
+			var o bool
+			tree, o = tree.Delete(k)
+			if _, has := m[k]; has != o {
+				t.Fatalf("delete key %v result not match", k)
+			}
+			delete(m, k)
+		} else {
+			tree = tree.Insert(k, i)
+			m[k] = i
+		}
+		if i%1000 == 0 {
+			snapshots = append(snapshots, tree)
+			expects = append(expects, persistentKeys(tree))
+		}
+	}
+
+	checkPersistent(t, tree, tree.root)
+	if int(tree.Len()) != len(m) {
+		t.Fatalf("tree length %v not equal to %v", tree.Len(), len(m))
+	}
+	for k, v := range m {
+		if tv, o := tree.Get(k); !o || tv != v {
+			t.Fatalf("get key %v got (%v, %v), expect %v", k, tv, o, v)
+		}
+	}
+
+	// 后续的修改不能影响之前的快照
+	for i, s := range snapshots {
+		checkPersistent(t, s, s.root)
+		keys := persistentKeys(s)
+		if len(keys) != len(expects[i]) || int(s.Len()) != len(keys) {
+			t.Fatalf("snapshot %v length changed", i)
+		}
+		for j := range keys {
+			if keys[j] != expects[i][j] {
+				t.Fatalf("snapshot %v changed at %v", i, j)
+			}
+		}
+	}
+
+	keys := persistentKeys(tree)
+	if !sort.IntsAreSorted(keys) {
+		t.Fatalf("keys not sorted")
+	}
+	var ranged []int
+	tree.Range(1000, 2000, func(k, _ int) bool {
+		ranged = append(ranged, k)
+		return true
+	})
+	var expect []int
+	for _, k := range keys {
+		if k >= 1000 && k <= 2000 {
+			expect = append(expect, k)
+		}
+	}
+	if len(ranged) != len(expect) {
+		t.Fatalf("range got %v keys, expect %v", len(ranged), len(expect))
+	}
+
+	for _, k := range keys {
+		tree, _ = tree.Delete(k)
+	}
+	if tree.Len() != 0 || tree.root != nil {
+		t.Fatalf("tree must be empty after delete all")
+	}
+}
+
+func TestPersistentRBTreeConcurrentRead(t *testing.T) {
+	var (
+		p    atomic.Pointer[PersistentRBTree[int, int]]
+		wg   sync.WaitGroup
+		stop int32
+	)
+	p.Store(NewPersistentRBTree[int, int]())
+
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&stop) == 0 {
+				s := p.Load()
+				var (
+					n    int32
+					last = -1
+				)
+				s.Each(func(k, v int) bool {
+					if k <= last || k != v {
+						t.Errorf("snapshot broken at key %v", k)
+						return false
+					}
+					last = k
+					n++
+					return true
+				})
+				if n != s.Len() {
+					t.Errorf("snapshot length %v not equal to %v", s.Len(), n)
+				}
+			}
+		}()
+	}
+
+	tree := p.Load()
+	for i := 0; i < 20000; i++ {
+		if i%4 == 3 {
+			tree, _ = tree.Delete(i - 3)
+		} else {
+			tree = tree.Insert(i, i)
+		}
+		p.Store(tree)
+	}
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+}
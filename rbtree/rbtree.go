@@ -12,6 +12,7 @@ const (
 type rbnode struct {
 	value  NodeValue
 	color  uint8
+	size   uint32 // 以该节点为根的子树节点数
 	left   *rbnode
 	right  *rbnode
 	parent *rbnode
@@ -49,6 +50,10 @@ func (this *rbnode) set_color(color uint8) {
 	this.color = color
 }
 
+func (this *rbnode) update_size() {
+	this.size = this.left.size + this.right.size + 1
+}
+
 func (this *rbnode) get_uncle() *rbnode {
 	if this.parent == nil || this.parent.parent == nil {
 		return nil
@@ -141,7 +146,14 @@ func (this *RBTree) rotate_left(node *rbnode) bool {
 	right_left := node_right.left
 	node_right.left = node
 	node.right = right_left
-	right_left.parent = node
+	// nil节点是共享的，不能修改它的父节点，delete_fixup依赖它
+	if !is_nil(right_left) {
+		right_left.parent = node
+	}
+
+	// 先更新下沉的节点，再更新上升的节点
+	node.update_size()
+	node_right.update_size()
 
 	if node == this.root {
 		this.root = node_right
@@ -174,7 +186,12 @@ func (this *RBTree) rotate_right(node *rbnode) bool {
 	left_right := node_left.right
 	node_left.right = node
 	node.left = left_right
-	left_right.parent = node
+	if !is_nil(left_right) {
+		left_right.parent = node
+	}
+
+	node.update_size()
+	node_left.update_size()
 
 	if node == this.root {
 		this.root = node_left
@@ -192,6 +209,8 @@ func (this *RBTree) insert_fixup(node *rbnode, left_or_right bool) {
 
 		uncle := node.get_uncle()
 		grandparent := node.get_grandparent()
+		// 变色后node会上移到祖父节点，需要重新判断是左还是右子节点
+		left_or_right = node == parent.left
 
 		if uncle.color_is_red() { // 叔父节点是红色
 			// 变色
@@ -248,6 +267,7 @@ func (this *RBTree) Insert(value NodeValue) {
 	node := &rbnode{
 		value:  value,
 		color:  NODE_COLOR_RED,
+		size:   1,
 		left:   nil_node,
 		right:  nil_node,
 		parent: insert_parent,
@@ -269,6 +289,11 @@ func (this *RBTree) Insert(value NodeValue) {
 		insert_parent.right = node
 	}
 
+	// 插入路径上的祖先节点子树大小加一
+	for p := insert_parent; p != nil; p = p.parent {
+		p.size += 1
+	}
+
 	this.insert_fixup(node, left_or_right)
 
 	return
@@ -415,7 +440,7 @@ func (this *RBTree) Delete(value NodeValue) bool {
 
 	// 把后继节点的子节点跟它的父节点关联上
 	child.parent = su.parent
-	if su.parent.is_root() {
+	if su.is_root() {
 		this.root = child
 	} else if su == su.parent.left {
 		su.parent.left = child
@@ -428,11 +453,24 @@ func (this *RBTree) Delete(value NodeValue) bool {
 		node.value = su.value
 	}
 
+	// 被删除节点的祖先节点子树大小减一
+	for p := su.parent; p != nil; p = p.parent {
+		p.size -= 1
+	}
+
+	this.node_num -= 1
+
 	// 删除的后继节点为黑色时调整
 	if su.color_is_black() {
 		this.delete_fixup(child)
 	}
 
+	// 删除了最后一个节点
+	if is_nil(this.root) {
+		this.root = nil
+	}
+	nil_node.parent = nil
+
 	return true
 }
 
@@ -472,6 +510,72 @@ func (this *RBTree) NodeNum() uint32 {
 	return this.node_num
 }
 
+// _count_less 小于value(or_equal为true时小于等于)的节点数
+func (this *RBTree) _count_less(value NodeValue, or_equal bool) uint32 {
+	var count uint32
+	node := this.root
+	for !is_nil(node) {
+		if value.Less(node.value) {
+			node = node.left
+		} else if node.value.Less(value) {
+			count += node.left.size + 1
+			node = node.right
+		} else {
+			count += node.left.size
+			if or_equal {
+				count += 1
+			}
+			break
+		}
+	}
+	return count
+}
+
+// Rank 获取value的排名，从1开始，不存在返回0
+func (this *RBTree) Rank(value NodeValue) uint32 {
+	var rank uint32
+	node := this.root
+	for !is_nil(node) {
+		if value.Less(node.value) {
+			node = node.left
+		} else if node.value.Less(value) {
+			rank += node.left.size + 1
+			node = node.right
+		} else {
+			return rank + node.left.size + 1
+		}
+	}
+	return 0
+}
+
+// Select 获取排名为rank的值，rank从1开始，超出范围返回nil
+func (this *RBTree) Select(rank uint32) NodeValue {
+	if rank == 0 || rank > this.node_num {
+		return nil
+	}
+	node := this.root
+	for !is_nil(node) {
+		left_size := node.left.size
+		if rank <= left_size {
+			node = node.left
+		} else if rank == left_size+1 {
+			return node.value
+		} else {
+			rank -= left_size + 1
+			node = node.right
+		}
+	}
+	return nil
+}
+
+// CountRange 值在[lo, hi]区间内的节点数
+func (this *RBTree) CountRange(lo, hi NodeValue) uint32 {
+	if hi.Less(lo) {
+		return 0
+	}
+	return this._count_less(hi, true) - this._count_less(lo, false)
+}
+
 type stack struct {
 	node_list []*rbnode
 	top       int
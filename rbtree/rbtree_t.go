@@ -0,0 +1,570 @@
+package rbtree
+
+import "golang.org/x/exp/constraints"
+
+type rbnodeT[K, V any] struct {
+	key    K
+	value  V
+	color  uint8
+	left   *rbnodeT[K, V]
+	right  *rbnodeT[K, V]
+	parent *rbnodeT[K, V]
+}
+
+func isRedT[K, V any](n *rbnodeT[K, V]) bool {
+	return n != nil && n.color == NODE_COLOR_RED
+}
+
+func isBlackT[K, V any](n *rbnodeT[K, V]) bool {
+	return n == nil || n.color == NODE_COLOR_BLACK
+}
+
+func minimumT[K, V any](n *rbnodeT[K, V]) *rbnodeT[K, V] {
+	for n.left != nil {
+		n = n.left
+	}
+	return n
+}
+
+func maximumT[K, V any](n *rbnodeT[K, V]) *rbnodeT[K, V] {
+	for n.right != nil {
+		n = n.right
+	}
+	return n
+}
+
+func successorT[K, V any](n *rbnodeT[K, V]) *rbnodeT[K, V] {
+	if n.right != nil {
+		return minimumT(n.right)
+	}
+	p := n.parent
+	for p != nil && n == p.right {
+		n = p
+		p = p.parent
+	}
+	return p
+}
+
+func predecessorT[K, V any](n *rbnodeT[K, V]) *rbnodeT[K, V] {
+	if n.left != nil {
+		return maximumT(n.left)
+	}
+	p := n.parent
+	for p != nil && n == p.left {
+		n = p
+		p = p.parent
+	}
+	return p
+}
+
+// IteratorT 有序树的双向迭代器，End()/REnd()为无效迭代器
+type IteratorT[K, V any] struct {
+	n *rbnodeT[K, V]
+}
+
+func (iter IteratorT[K, V]) Key() K {
+	return iter.n.key
+}
+
+func (iter IteratorT[K, V]) Value() V {
+	return iter.n.value
+}
+
+func (iter IteratorT[K, V]) SetValue(value V) {
+	iter.n.value = value
+}
+
+func (iter IteratorT[K, V]) Next() IteratorT[K, V] {
+	if iter.n == nil {
+		return iter
+	}
+	return IteratorT[K, V]{n: successorT(iter.n)}
+}
+
+func (iter IteratorT[K, V]) Prev() IteratorT[K, V] {
+	if iter.n == nil {
+		return iter
+	}
+	return IteratorT[K, V]{n: predecessorT(iter.n)}
+}
+
+func (iter IteratorT[K, V]) IsValid() bool {
+	return iter.n != nil
+}
+
+// RBTreeT 泛型红黑树，按key有序
+type RBTreeT[K, V any] struct {
+	root   *rbnodeT[K, V]
+	length int32
+	less   func(K, K) bool
+}
+
+func NewRBTreeT[K constraints.Ordered, V any]() *RBTreeT[K, V] {
+	return &RBTreeT[K, V]{
+		less: func(a, b K) bool { return a < b },
+	}
+}
+
+func NewRBTreeTWithLess[K, V any](less func(K, K) bool) *RBTreeT[K, V] {
+	if less == nil {
+		panic("ponu.rbtree: RBTreeT need less function")
+	}
+	return &RBTreeT[K, V]{
+		less: less,
+	}
+}
+
+func (t *RBTreeT[K, V]) Len() int32 {
+	return t.length
+}
+
+func (t *RBTreeT[K, V]) Clear() {
+	t.root = nil
+	t.length = 0
+}
+
+func (t *RBTreeT[K, V]) rotateLeft(n *rbnodeT[K, V]) {
+	r := n.right
+	n.right = r.left
+	if r.left != nil {
+		r.left.parent = n
+	}
+	r.parent = n.parent
+	if n.parent == nil {
+		t.root = r
+	} else if n == n.parent.left {
+		n.parent.left = r
+	} else {
+		n.parent.right = r
+	}
+	r.left = n
+	n.parent = r
+}
+
+func (t *RBTreeT[K, V]) rotateRight(n *rbnodeT[K, V]) {
+	l := n.left
+	n.left = l.right
+	if l.right != nil {
+		l.right.parent = n
+	}
+	l.parent = n.parent
+	if n.parent == nil {
+		t.root = l
+	} else if n == n.parent.right {
+		n.parent.right = l
+	} else {
+		n.parent.left = l
+	}
+	l.right = n
+	n.parent = l
+}
+
+// Insert 插入，key已存在则替换value，返回是否新增了节点
+func (t *RBTreeT[K, V]) Insert(key K, value V) bool {
+	var (
+		parent *rbnodeT[K, V]
+		n      = t.root
+		left   bool
+	)
+	for n != nil {
+		parent = n
+		if t.less(key, n.key) {
+			n = n.left
+			left = true
+		} else if t.less(n.key, key) {
+			n = n.right
+			left = false
+		} else {
+			n.value = value
+			return false
+		}
+	}
+
+	node := &rbnodeT[K, V]{
+		key:    key,
+		value:  value,
+		color:  NODE_COLOR_RED,
+		parent: parent,
+	}
+	if parent == nil {
+		t.root = node
+	} else if left {
+		parent.left = node
+	} else {
+		parent.right = node
+	}
+	t.length += 1
+	t.insertFixup(node)
+	return true
+}
+
+func (t *RBTreeT[K, V]) insertFixup(n *rbnodeT[K, V]) {
+	for isRedT(n.parent) {
+		parent := n.parent
+		grandparent := parent.parent
+		if parent == grandparent.left {
+			uncle := grandparent.right
+			if isRedT(uncle) { // 叔父节点是红色，变色
+				parent.color = NODE_COLOR_BLACK
+				uncle.color = NODE_COLOR_BLACK
+				grandparent.color = NODE_COLOR_RED
+				n = grandparent
+				continue
+			}
+			if n == parent.right { // 插入节点是父节点的右子节点，左旋
+				n = parent
+				t.rotateLeft(n)
+				parent = n.parent
+			}
+			parent.color = NODE_COLOR_BLACK
+			grandparent.color = NODE_COLOR_RED
+			t.rotateRight(grandparent)
+		} else {
+			uncle := grandparent.left
+			if isRedT(uncle) {
+				parent.color = NODE_COLOR_BLACK
+				uncle.color = NODE_COLOR_BLACK
+				grandparent.color = NODE_COLOR_RED
+				n = grandparent
+				continue
+			}
+			if n == parent.left { // 插入节点是父节点的左子节点，右旋
+				n = parent
+				t.rotateRight(n)
+				parent = n.parent
+			}
+			parent.color = NODE_COLOR_BLACK
+			grandparent.color = NODE_COLOR_RED
+			t.rotateLeft(grandparent)
+		}
+	}
+	t.root.color = NODE_COLOR_BLACK
+}
+
+// transplant 用节点v替换节点u在树中的位置
+func (t *RBTreeT[K, V]) transplant(u, v *rbnodeT[K, V]) {
+	if u.parent == nil {
+		t.root = v
+	} else if u == u.parent.left {
+		u.parent.left = v
+	} else {
+		u.parent.right = v
+	}
+	if v != nil {
+		v.parent = u.parent
+	}
+}
+
+// deleteNode 删除节点，通过重新链接节点而不是拷贝key和value，其他节点的迭代器保持有效
+func (t *RBTreeT[K, V]) deleteNode(z *rbnodeT[K, V]) {
+	var (
+		x, xParent *rbnodeT[K, V]
+		y          = z
+		yColor     = y.color
+	)
+	if z.left == nil {
+		x, xParent = z.right, z.parent
+		t.transplant(z, z.right)
+	} else if z.right == nil {
+		x, xParent = z.left, z.parent
+		t.transplant(z, z.left)
+	} else {
+		// 两个子节点都存在则用后继节点替换
+		y = minimumT(z.right)
+		yColor = y.color
+		x = y.right
+		if y.parent == z {
+			xParent = y
+		} else {
+			xParent = y.parent
+			t.transplant(y, y.right)
+			y.right = z.right
+			y.right.parent = y
+		}
+		t.transplant(z, y)
+		y.left = z.left
+		y.left.parent = y
+		y.color = z.color
+	}
+	z.left, z.right, z.parent = nil, nil, nil
+	t.length -= 1
+
+	// 删除的节点为黑色时调整
+	if yColor == NODE_COLOR_BLACK {
+		t.deleteFixup(x, xParent)
+	}
+}
+
+func (t *RBTreeT[K, V]) deleteFixup(n, parent *rbnodeT[K, V]) {
+	for n != t.root && isBlackT(n) {
+		if n == parent.left {
+			s := parent.right
+			if isRedT(s) {
+				s.color = NODE_COLOR_BLACK
+				parent.color = NODE_COLOR_RED
+				t.rotateLeft(parent)
+				s = parent.right
+			}
+			if isBlackT(s.left) && isBlackT(s.right) {
+				s.color = NODE_COLOR_RED
+				n = parent
+				parent = n.parent
+			} else {
+				if isBlackT(s.right) {
+					s.left.color = NODE_COLOR_BLACK
+					s.color = NODE_COLOR_RED
+					t.rotateRight(s)
+					s = parent.right
+				}
+				s.color = parent.color
+				parent.color = NODE_COLOR_BLACK
+				s.right.color = NODE_COLOR_BLACK
+				t.rotateLeft(parent)
+				n = t.root
+			}
+		} else {
+			s := parent.left
+			if isRedT(s) {
+				s.color = NODE_COLOR_BLACK
+				parent.color = NODE_COLOR_RED
+				t.rotateRight(parent)
+				s = parent.left
+			}
+			if isBlackT(s.left) && isBlackT(s.right) {
+				s.color = NODE_COLOR_RED
+				n = parent
+				parent = n.parent
+			} else {
+				if isBlackT(s.left) {
+					s.right.color = NODE_COLOR_BLACK
+					s.color = NODE_COLOR_RED
+					t.rotateLeft(s)
+					s = parent.left
+				}
+				s.color = parent.color
+				parent.color = NODE_COLOR_BLACK
+				s.left.color = NODE_COLOR_BLACK
+				t.rotateRight(parent)
+				n = t.root
+			}
+		}
+	}
+	if n != nil {
+		n.color = NODE_COLOR_BLACK
+	}
+}
+
+func (t *RBTreeT[K, V]) Delete(key K) bool {
+	n := t.find(key)
+	if n == nil {
+		return false
+	}
+	t.deleteNode(n)
+	return true
+}
+
+// DeleteIter 删除迭代器指向的节点，返回下一个迭代器
+func (t *RBTreeT[K, V]) DeleteIter(iter IteratorT[K, V]) IteratorT[K, V] {
+	if iter.n == nil {
+		return iter
+	}
+	next := successorT(iter.n)
+	t.deleteNode(iter.n)
+	return IteratorT[K, V]{n: next}
+}
+
+func (t *RBTreeT[K, V]) find(key K) *rbnodeT[K, V] {
+	n := t.root
+	for n != nil {
+		if t.less(key, n.key) {
+			n = n.left
+		} else if t.less(n.key, key) {
+			n = n.right
+		} else {
+			return n
+		}
+	}
+	return nil
+}
+
+// lowerBound 第一个大于等于key的节点
+func (t *RBTreeT[K, V]) lowerBound(key K) *rbnodeT[K, V] {
+	var res *rbnodeT[K, V]
+	n := t.root
+	for n != nil {
+		if t.less(n.key, key) {
+			n = n.right
+		} else {
+			res = n
+			n = n.left
+		}
+	}
+	return res
+}
+
+// upperBound 第一个大于key的节点
+func (t *RBTreeT[K, V]) upperBound(key K) *rbnodeT[K, V] {
+	var res *rbnodeT[K, V]
+	n := t.root
+	for n != nil {
+		if t.less(key, n.key) {
+			res = n
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return res
+}
+
+// lastLess 最后一个小于key的节点
+func (t *RBTreeT[K, V]) lastLess(key K) *rbnodeT[K, V] {
+	var res *rbnodeT[K, V]
+	n := t.root
+	for n != nil {
+		if t.less(n.key, key) {
+			res = n
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return res
+}
+
+// lastLessEqual 最后一个小于等于key的节点
+func (t *RBTreeT[K, V]) lastLessEqual(key K) *rbnodeT[K, V] {
+	var res *rbnodeT[K, V]
+	n := t.root
+	for n != nil {
+		if t.less(key, n.key) {
+			n = n.left
+		} else {
+			res = n
+			n = n.right
+		}
+	}
+	return res
+}
+
+func nodeResultT[K, V any](n *rbnodeT[K, V]) (K, V, bool) {
+	if n == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	return n.key, n.value, true
+}
+
+func (t *RBTreeT[K, V]) Get(key K) (V, bool) {
+	n := t.find(key)
+	if n == nil {
+		var v V
+		return v, false
+	}
+	return n.value, true
+}
+
+func (t *RBTreeT[K, V]) Has(key K) bool {
+	return t.find(key) != nil
+}
+
+func (t *RBTreeT[K, V]) Min() (K, V, bool) {
+	if t.root == nil {
+		return nodeResultT[K, V](nil)
+	}
+	return nodeResultT(minimumT(t.root))
+}
+
+func (t *RBTreeT[K, V]) Max() (K, V, bool) {
+	if t.root == nil {
+		return nodeResultT[K, V](nil)
+	}
+	return nodeResultT(maximumT(t.root))
+}
+
+// Floor 小于等于key的最大元素
+func (t *RBTreeT[K, V]) Floor(key K) (K, V, bool) {
+	return nodeResultT(t.lastLessEqual(key))
+}
+
+// Ceiling 大于等于key的最小元素
+func (t *RBTreeT[K, V]) Ceiling(key K) (K, V, bool) {
+	return nodeResultT(t.lowerBound(key))
+}
+
+// Lower 小于key的最大元素
+func (t *RBTreeT[K, V]) Lower(key K) (K, V, bool) {
+	return nodeResultT(t.lastLess(key))
+}
+
+// Higher 大于key的最小元素
+func (t *RBTreeT[K, V]) Higher(key K) (K, V, bool) {
+	return nodeResultT(t.upperBound(key))
+}
+
+func (t *RBTreeT[K, V]) Begin() IteratorT[K, V] {
+	if t.root == nil {
+		return t.End()
+	}
+	return IteratorT[K, V]{n: minimumT(t.root)}
+}
+
+func (t *RBTreeT[K, V]) End() IteratorT[K, V] {
+	return IteratorT[K, V]{}
+}
+
+func (t *RBTreeT[K, V]) RBegin() IteratorT[K, V] {
+	if t.root == nil {
+		return t.REnd()
+	}
+	return IteratorT[K, V]{n: maximumT(t.root)}
+}
+
+func (t *RBTreeT[K, V]) REnd() IteratorT[K, V] {
+	return IteratorT[K, V]{}
+}
+
+// Find 查找key对应的迭代器，不存在返回End()
+func (t *RBTreeT[K, V]) Find(key K) IteratorT[K, V] {
+	return IteratorT[K, V]{n: t.find(key)}
+}
+
+// LowerBound 第一个大于等于key的迭代器
+func (t *RBTreeT[K, V]) LowerBound(key K) IteratorT[K, V] {
+	return IteratorT[K, V]{n: t.lowerBound(key)}
+}
+
+// UpperBound 第一个大于key的迭代器
+func (t *RBTreeT[K, V]) UpperBound(key K) IteratorT[K, V] {
+	return IteratorT[K, V]{n: t.upperBound(key)}
+}
+
+// Each 按key从小到大遍历，f返回false则停止
+func (t *RBTreeT[K, V]) Each(f func(key K, value V) bool) {
+	for iter := t.Begin(); iter.IsValid(); iter = iter.Next() {
+		if !f(iter.n.key, iter.n.value) {
+			return
+		}
+	}
+}
+
+// ReverseEach 按key从大到小遍历，f返回false则停止
+func (t *RBTreeT[K, V]) ReverseEach(f func(key K, value V) bool) {
+	for iter := t.RBegin(); iter.IsValid(); iter = iter.Prev() {
+		if !f(iter.n.key, iter.n.value) {
+			return
+		}
+	}
+}
+
+// Range 按key从小到大遍历[from, to]区间，f返回false则停止
+func (t *RBTreeT[K, V]) Range(from, to K, f func(key K, value V) bool) {
+	for n := t.lowerBound(from); n != nil && !t.less(to, n.key); n = successorT(n) {
+		if !f(n.key, n.value) {
+			return
+		}
+	}
+}
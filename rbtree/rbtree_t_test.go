@@ -0,0 +1,179 @@
+package rbtree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// checkRBTreeT 校验红黑树性质，返回黑高
+func checkRBTreeT[K, V any](t *testing.T, tree *RBTreeT[K, V], n *rbnodeT[K, V]) int {
+	if n == nil {
+		return 1
+	}
+	if n.left != nil {
+		if n.left.parent != n {
+			t.Fatalf("left child parent pointer broken")
+		}
+		if !tree.less(n.left.key, n.key) {
+			t.Fatalf("left child key %v not less than %v", n.left.key, n.key)
+		}
+	}
+	if n.right != nil {
+		if n.right.parent != n {
+			t.Fatalf("right child parent pointer broken")
+		}
+		if !tree.less(n.key, n.right.key) {
+			t.Fatalf("right child key %v not greater than %v", n.right.key, n.key)
+		}
+	}
+	if isRedT(n) && (isRedT(n.left) || isRedT(n.right)) {
+		t.Fatalf("red node %v has red child", n.key)
+	}
+	lh := checkRBTreeT(t, tree, n.left)
+	rh := checkRBTreeT(t, tree, n.right)
+	if lh != rh {
+		t.Fatalf("black height of node %v not balanced: %v != %v", n.key, lh, rh)
+	}
+	if isBlackT(n) {
+		lh += 1
+	}
+	return lh
+}
+
+func TestRBTreeTInsertDelete(t *testing.T) {
+	var (
+		tree = NewRBTreeT[int, int]()
+		m    = make(map[int]int)
+		r    = rand.New(rand.NewSource(time.Now().Unix()))
+	)
+	for i := 0; i < 20000; i++ {
+		k := r.Intn(5000)
+		if r.Intn(3) == 0 {
+			_, o := m[k]
+			if tree.Delete(k) != o {
+				t.Fatalf("delete key %v result not match", k)
+			}
+			delete(m, k)
+		} else {
+			_, o := m[k]
+			if tree.Insert(k, i) == o {
+				t.Fatalf("insert key %v result not match", k)
+			}
+			m[k] = i
+		}
+	}
+	if tree.root != nil && tree.root.color != NODE_COLOR_BLACK {
+		t.Fatalf("root must be black")
+	}
+	checkRBTreeT(t, tree, tree.root)
+	if int(tree.Len()) != len(m) {
+		t.Fatalf("tree length %v not equal to %v", tree.Len(), len(m))
+	}
+
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	i := 0
+	for iter := tree.Begin(); iter != tree.End(); iter = iter.Next() {
+		if iter.Key() != keys[i] || iter.Value() != m[keys[i]] {
+			t.Fatalf("iterator %v got (%v, %v), expect (%v, %v)", i, iter.Key(), iter.Value(), keys[i], m[keys[i]])
+		}
+		i++
+	}
+	i = len(keys) - 1
+	for iter := tree.RBegin(); iter != tree.REnd(); iter = iter.Prev() {
+		if iter.Key() != keys[i] {
+			t.Fatalf("reverse iterator %v got %v, expect %v", i, iter.Key(), keys[i])
+		}
+		i--
+	}
+}
+
+func TestRBTreeTBound(t *testing.T) {
+	tree := NewRBTreeT[int, string]()
+	for _, k := range []int{10, 20, 30, 40, 50} {
+		tree.Insert(k, "")
+	}
+
+	cases := []struct {
+		name string
+		f    func(int) (int, string, bool)
+		key  int
+		res  int
+		ok   bool
+	}{
+		{"Floor", tree.Floor, 25, 20, true},
+		{"Floor", tree.Floor, 30, 30, true},
+		{"Floor", tree.Floor, 5, 0, false},
+		{"Ceiling", tree.Ceiling, 25, 30, true},
+		{"Ceiling", tree.Ceiling, 30, 30, true},
+		{"Ceiling", tree.Ceiling, 55, 0, false},
+		{"Lower", tree.Lower, 30, 20, true},
+		{"Lower", tree.Lower, 10, 0, false},
+		{"Higher", tree.Higher, 30, 40, true},
+		{"Higher", tree.Higher, 50, 0, false},
+	}
+	for _, c := range cases {
+		k, _, o := c.f(c.key)
+		if o != c.ok || k != c.res {
+			t.Fatalf("%v(%v) got (%v, %v), expect (%v, %v)", c.name, c.key, k, o, c.res, c.ok)
+		}
+	}
+
+	if k, _, _ := tree.Min(); k != 10 {
+		t.Fatalf("min got %v", k)
+	}
+	if k, _, _ := tree.Max(); k != 50 {
+		t.Fatalf("max got %v", k)
+	}
+
+	var keys []int
+	tree.Range(15, 40, func(k int, _ string) bool {
+		keys = append(keys, k)
+		return k < 30
+	})
+	if len(keys) != 2 || keys[0] != 20 || keys[1] != 30 {
+		t.Fatalf("range got %v", keys)
+	}
+
+	for iter := tree.Begin(); iter.IsValid(); {
+		if iter.Key()%20 == 0 {
+			iter = tree.DeleteIter(iter)
+		} else {
+			iter = iter.Next()
+		}
+	}
+	keys = keys[:0]
+	tree.Each(func(k int, _ string) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != 3 || keys[0] != 10 || keys[1] != 30 || keys[2] != 50 {
+		t.Fatalf("after delete by iterator got %v", keys)
+	}
+}
+
+func TestRBTreeTWithLess(t *testing.T) {
+	type pos struct{ x, y int }
+	tree := NewRBTreeTWithLess[pos, int](func(a, b pos) bool {
+		if a.x != b.x {
+			return a.x < b.x
+		}
+		return a.y < b.y
+	})
+	tree.Insert(pos{1, 2}, 1)
+	tree.Insert(pos{0, 5}, 2)
+	tree.Insert(pos{1, 1}, 3)
+	var values []int
+	tree.ReverseEach(func(_ pos, v int) bool {
+		values = append(values, v)
+		return true
+	})
+	if len(values) != 3 || values[0] != 1 || values[1] != 3 || values[2] != 2 {
+		t.Fatalf("reverse each got %v", values)
+	}
+}
@@ -1,7 +1,10 @@
 package rbtree
 
 import (
+	"math/rand"
+	"sort"
 	"testing"
+	"time"
 )
 
 type KeyValue struct {
@@ -162,3 +165,78 @@ func Test_output_tree2(t *testing.T) {
 	t.Logf("after delete nodes:")
 	output_nodes(rb.root, t)
 }
+
+func check_size(node *rbnode, t *testing.T) uint32 {
+	if is_nil(node) {
+		return 0
+	}
+	size := check_size(node.left, t) + check_size(node.right, t) + 1
+	if node.size != size {
+		t.Fatalf("node %v size %v not equal to %v", node.value.(*KeyValue).Key, node.size, size)
+	}
+	return size
+}
+
+func Test_rank_select(t *testing.T) {
+	var (
+		rb RBTree
+		m  = make(map[int]bool)
+		r  = rand.New(rand.NewSource(time.Now().Unix()))
+	)
+	for i := 0; i < 20000; i++ {
+		k := r.Intn(3000)
+		if r.Intn(3) == 0 {
+			if rb.Delete(&KeyValue{Key: k}) != m[k] {
+				t.Fatalf("delete key %v result not match", k)
+			}
+			delete(m, k)
+		} else {
+			rb.Insert(&KeyValue{Key: k})
+			m[k] = true
+		}
+	}
+	if rb.NodeNum() != uint32(len(m)) {
+		t.Fatalf("node num %v not equal to %v", rb.NodeNum(), len(m))
+	}
+	if check_size(rb.root, t) != rb.NodeNum() {
+		t.Fatalf("root size not equal to node num")
+	}
+
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	for i, k := range keys {
+		if rank := rb.Rank(&KeyValue{Key: k}); rank != uint32(i+1) {
+			t.Fatalf("key %v rank %v, expect %v", k, rank, i+1)
+		}
+		if v := rb.Select(uint32(i + 1)); v == nil || v.(*KeyValue).Key != k {
+			t.Fatalf("select %v got %v, expect %v", i+1, v, k)
+		}
+	}
+	if rb.Select(0) != nil || rb.Select(rb.NodeNum()+1) != nil {
+		t.Fatalf("select out of range must return nil")
+	}
+	if rb.Rank(&KeyValue{Key: -1}) != 0 {
+		t.Fatalf("rank of absent key must be 0")
+	}
+
+	lo, hi := 1000, 2000
+	var count uint32
+	for _, k := range keys {
+		if k >= lo && k <= hi {
+			count++
+		}
+	}
+	if c := rb.CountRange(&KeyValue{Key: lo}, &KeyValue{Key: hi}); c != count {
+		t.Fatalf("count range [%v, %v] got %v, expect %v", lo, hi, c, count)
+	}
+
+	for _, k := range keys {
+		rb.Delete(&KeyValue{Key: k})
+	}
+	if rb.NodeNum() != 0 || rb.root != nil {
+		t.Fatalf("tree must be empty after delete all")
+	}
+}
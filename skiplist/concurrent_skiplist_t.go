@@ -0,0 +1,443 @@
+package skiplist
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type cskiplistLayer[K, V any] struct {
+	next atomic.Pointer[cskiplistItem[K, V]]
+	span atomic.Int32
+}
+
+type cskiplistItem[K, V any] struct {
+	key      K
+	value    V
+	backward atomic.Pointer[cskiplistItem[K, V]]
+	layers   []cskiplistLayer[K, V]
+}
+
+func newCSkiplistItem[K, V any](layer int32, key K, value V) *cskiplistItem[K, V] {
+	return &cskiplistItem[K, V]{
+		key:    key,
+		value:  value,
+		layers: make([]cskiplistLayer[K, V], layer),
+	}
+}
+
+// ConcurrentSkiplistT 并发跳表，排序规则同SkiplistT
+// 写操作之间用互斥锁串行，节点的指针和跨度都是原子变量，写操作前后各把序号加一
+// Has、Get和RangeByScore不加锁，不会被写操作阻塞，但结果是弱一致的
+// 排名相关的读操作先乐观读：序号为偶数且前后不变时才返回结果，
+// 连续maxOptimisticReads次被写操作打断后改为加写锁读，所以不是无锁的，
+// 写操作频繁时读操作会和写操作竞争互斥锁，但不会无限重试
+type ConcurrentSkiplistT[K, V any] struct {
+	mutex      sync.Mutex
+	seq        atomic.Uint64
+	currLayer  atomic.Int32
+	currLength atomic.Int32
+	head       *cskiplistItem[K, V]
+	tail       atomic.Pointer[cskiplistItem[K, V]]
+	beforeNode []*cskiplistItem[K, V] // 写操作缓存插入之前或删除之前的节点
+	rank       []int32                // 写操作缓存排名
+	rand       *rand.Rand
+	less       func(V, V) bool
+	equal      func(K, K) bool
+}
+
+// NewConcurrentSkiplistT ...
+func NewConcurrentSkiplistT[K, V any](less func(V, V) bool, equal func(K, K) bool) *ConcurrentSkiplistT[K, V] {
+	if less == nil || equal == nil {
+		panic("ponu.skiplist: ConcurrentSkiplistT need less and equal function")
+	}
+	var (
+		k K
+		v V
+	)
+	s := &ConcurrentSkiplistT[K, V]{
+		head:       newCSkiplistItem[K, V](MaxSkiplistLayer, k, v),
+		beforeNode: make([]*cskiplistItem[K, V], MaxSkiplistLayer),
+		rank:       make([]int32, MaxSkiplistLayer),
+		rand:       rand.New(rand.NewSource(time.Now().Unix())),
+		less:       less,
+		equal:      equal,
+	}
+	s.currLayer.Store(1)
+	return s
+}
+
+func (s *ConcurrentSkiplistT[K, V]) randomSkiplistLayer() int32 {
+	n := int32(1)
+	r := s.rand.Int31()
+	for r%2 == 0 {
+		n++
+		r /= 2
+	}
+	if n > MaxSkiplistLayer {
+		n = MaxSkiplistLayer
+	}
+	return n
+}
+
+// beginWrite 加锁并把序号变为奇数，表示正在写
+func (s *ConcurrentSkiplistT[K, V]) beginWrite() {
+	s.mutex.Lock()
+	s.seq.Add(1)
+}
+
+func (s *ConcurrentSkiplistT[K, V]) endWrite() {
+	s.seq.Add(1)
+	s.mutex.Unlock()
+}
+
+// 乐观读的最大尝试次数，超过后加锁读
+const maxOptimisticReads = 8
+
+// read 乐观读，f执行期间没有写操作才返回，否则重试，
+// 重试maxOptimisticReads次仍失败时加锁执行f，避免写操作频繁时读操作一直饥饿
+func (s *ConcurrentSkiplistT[K, V]) read(f func()) {
+	for i := 0; i < maxOptimisticReads; i++ {
+		seq := s.seq.Load()
+		if seq&1 == 0 {
+			f()
+			if s.seq.Load() == seq {
+				return
+			}
+		}
+		runtime.Gosched()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f()
+}
+
+// Insert 插入元素，返回插入后的排名，不检查key是否已存在
+func (s *ConcurrentSkiplistT[K, V]) Insert(key K, value V) int32 {
+	s.beginWrite()
+	defer s.endWrite()
+
+	currLayer := s.currLayer.Load()
+	currLength := s.currLength.Load()
+	node := s.head
+	for i := currLayer - 1; i >= 0; i-- {
+		if i == currLayer-1 {
+			s.rank[i] = 0
+		} else {
+			s.rank[i] = s.rank[i+1]
+		}
+		for next := node.layers[i].next.Load(); next != nil && !s.less(value, next.value); next = node.layers[i].next.Load() {
+			s.rank[i] += node.layers[i].span.Load()
+			node = next
+		}
+		s.beforeNode[i] = node
+	}
+
+	newLayer := s.randomSkiplistLayer()
+	if newLayer > currLayer {
+		for i := currLayer; i < newLayer; i++ {
+			s.rank[i] = 0
+			s.beforeNode[i] = s.head
+			s.head.layers[i].span.Store(currLength)
+		}
+		s.currLayer.Store(newLayer)
+	}
+
+	newNode := newCSkiplistItem(newLayer, key, value)
+	// 先设置好新节点所有层的后继，再从下往上链接到跳表中
+	for i := int32(0); i < newLayer; i++ {
+		before := s.beforeNode[i]
+		newNode.layers[i].next.Store(before.layers[i].next.Load())
+		newNode.layers[i].span.Store(before.layers[i].span.Load() - (s.rank[0] - s.rank[i]))
+	}
+	if s.beforeNode[0] != s.head {
+		newNode.backward.Store(s.beforeNode[0])
+	}
+	for i := int32(0); i < newLayer; i++ {
+		before := s.beforeNode[i]
+		before.layers[i].span.Store((s.rank[0] - s.rank[i]) + 1)
+		before.layers[i].next.Store(newNode)
+	}
+	for i := newLayer; i < s.currLayer.Load(); i++ {
+		s.beforeNode[i].layers[i].span.Add(1)
+	}
+
+	if next := newNode.layers[0].next.Load(); next != nil {
+		next.backward.Store(newNode)
+	} else {
+		s.tail.Store(newNode)
+	}
+
+	s.currLength.Add(1)
+	return s.rank[0] + 1
+}
+
+// deleteByRank 调用前要先beginWrite
+func (s *ConcurrentSkiplistT[K, V]) deleteByRank(rank int32) *cskiplistItem[K, V] {
+	if rank <= 0 || rank > s.currLength.Load() {
+		return nil
+	}
+	var currRank int32
+	currLayer := s.currLayer.Load()
+	n := s.head
+	for i := currLayer - 1; i >= 0; i-- {
+		for next := n.layers[i].next.Load(); next != nil && currRank+n.layers[i].span.Load() < rank; next = n.layers[i].next.Load() {
+			currRank += n.layers[i].span.Load()
+			n = next
+		}
+		s.beforeNode[i] = n
+	}
+	node := n.layers[0].next.Load()
+
+	// 从上往下断开链接，被删除节点自身的后继保持不变，正在访问它的读操作可以继续往后走
+	for i := currLayer - 1; i >= 0; i-- {
+		before := s.beforeNode[i]
+		if before.layers[i].next.Load() == node {
+			before.layers[i].span.Add(node.layers[i].span.Load() - 1)
+			before.layers[i].next.Store(node.layers[i].next.Load())
+		} else {
+			before.layers[i].span.Add(-1)
+		}
+	}
+
+	if next := node.layers[0].next.Load(); next != nil {
+		next.backward.Store(node.backward.Load())
+	} else {
+		s.tail.Store(node.backward.Load())
+	}
+
+	for currLayer > 1 && s.head.layers[currLayer-1].next.Load() == nil {
+		currLayer--
+	}
+	s.currLayer.Store(currLayer)
+	s.currLength.Add(-1)
+	return node
+}
+
+// getNode 查找元素，返回节点和排名
+func (s *ConcurrentSkiplistT[K, V]) getNode(key K, value V) (*cskiplistItem[K, V], int32) {
+	var rank int32
+	n := s.head
+	for i := s.currLayer.Load() - 1; i >= 0; i-- {
+		for next := n.layers[i].next.Load(); next != nil && s.less(next.value, value); next = n.layers[i].next.Load() {
+			rank += n.layers[i].span.Load()
+			n = next
+		}
+	}
+	for n = n.layers[0].next.Load(); n != nil && !s.less(value, n.value); n = n.layers[0].next.Load() {
+		rank++
+		if s.equal(n.key, key) {
+			return n, rank
+		}
+	}
+	return nil, 0
+}
+
+func (s *ConcurrentSkiplistT[K, V]) getNodeByRank(rank int32) *cskiplistItem[K, V] {
+	if rank <= 0 {
+		return nil
+	}
+	n := s.head
+	currRank := int32(0)
+	for i := s.currLayer.Load() - 1; i >= 0; i-- {
+		for next := n.layers[i].next.Load(); next != nil && currRank+n.layers[i].span.Load() <= rank; next = n.layers[i].next.Load() {
+			currRank += n.layers[i].span.Load()
+			n = next
+		}
+		if currRank == rank {
+			return n
+		}
+	}
+	return nil
+}
+
+func (s *ConcurrentSkiplistT[K, V]) firstNotLess(min V) (*cskiplistItem[K, V], int32) {
+	var rank int32
+	n := s.head
+	for i := s.currLayer.Load() - 1; i >= 0; i-- {
+		for next := n.layers[i].next.Load(); next != nil && s.less(next.value, min); next = n.layers[i].next.Load() {
+			rank += n.layers[i].span.Load()
+			n = next
+		}
+	}
+	return n.layers[0].next.Load(), rank + 1
+}
+
+func (s *ConcurrentSkiplistT[K, V]) lastNotGreater(max V) int32 {
+	var rank int32
+	n := s.head
+	for i := s.currLayer.Load() - 1; i >= 0; i-- {
+		for next := n.layers[i].next.Load(); next != nil && !s.less(max, next.value); next = n.layers[i].next.Load() {
+			rank += n.layers[i].span.Load()
+			n = next
+		}
+	}
+	return rank
+}
+
+// Delete ...
+func (s *ConcurrentSkiplistT[K, V]) Delete(key K, value V) bool {
+	s.beginWrite()
+	defer s.endWrite()
+	_, rank := s.getNode(key, value)
+	if rank == 0 {
+		return false
+	}
+	s.deleteByRank(rank)
+	return true
+}
+
+// DeleteByRank ...
+func (s *ConcurrentSkiplistT[K, V]) DeleteByRank(rank int32) (K, V, bool) {
+	s.beginWrite()
+	defer s.endWrite()
+	node := s.deleteByRank(rank)
+	if node == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	return node.key, node.value, true
+}
+
+// DeleteTail ...
+func (s *ConcurrentSkiplistT[K, V]) DeleteTail() (K, V, bool) {
+	s.beginWrite()
+	defer s.endWrite()
+	node := s.deleteByRank(s.currLength.Load())
+	if node == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	return node.key, node.value, true
+}
+
+// Has 无锁查找元素是否存在，与同时进行的写操作相比是弱一致的
+func (s *ConcurrentSkiplistT[K, V]) Has(key K, value V) bool {
+	node, _ := s.getNode(key, value)
+	return node != nil
+}
+
+// Get 无锁查找key对应的value，跳表按value排序，所以要从头遍历，复杂度O(n)
+// 与同时进行的写操作相比是弱一致的，已知value时用Has更快
+func (s *ConcurrentSkiplistT[K, V]) Get(key K) (V, bool) {
+	for n := s.head.layers[0].next.Load(); n != nil; n = n.layers[0].next.Load() {
+		if s.equal(n.key, key) {
+			return n.value, true
+		}
+	}
+	var v V
+	return v, false
+}
+
+// GetRank 获取排名，从1开始，不存在返回0
+func (s *ConcurrentSkiplistT[K, V]) GetRank(key K, value V) int32 {
+	var rank int32
+	s.read(func() {
+		_, rank = s.getNode(key, value)
+	})
+	return rank
+}
+
+// GetByRank ...
+func (s *ConcurrentSkiplistT[K, V]) GetByRank(rank int32) (K, V, bool) {
+	var node *cskiplistItem[K, V]
+	s.read(func() {
+		node = s.getNodeByRank(rank)
+	})
+	if node == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	return node.key, node.value, true
+}
+
+// GetTail ...
+func (s *ConcurrentSkiplistT[K, V]) GetTail() (K, V, bool) {
+	node := s.tail.Load()
+	if node == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	return node.key, node.value, true
+}
+
+// GetByRankRange 从排名rankStart开始获取最多rankNum个元素
+func (s *ConcurrentSkiplistT[K, V]) GetByRankRange(rankStart, rankNum int32) []Pair[K, V] {
+	var pairs []Pair[K, V]
+	if rankNum <= 0 {
+		return pairs
+	}
+	s.read(func() {
+		pairs = pairs[:0]
+		for node := s.getNodeByRank(rankStart); node != nil && int32(len(pairs)) < rankNum; node = node.layers[0].next.Load() {
+			pairs = append(pairs, Pair[K, V]{k: node.key, v: node.value})
+		}
+	})
+	return pairs
+}
+
+// GetByScoreRange 获取value在[min, max]区间的元素，跳过前offset个，最多获取limit个，limit小于0表示不限制
+func (s *ConcurrentSkiplistT[K, V]) GetByScoreRange(min, max V, offset, limit int32) []Pair[K, V] {
+	var pairs []Pair[K, V]
+	if limit == 0 {
+		return pairs
+	}
+	s.read(func() {
+		pairs = pairs[:0]
+		node, _ := s.firstNotLess(min)
+		for i := int32(0); node != nil && !s.less(max, node.value); node = node.layers[0].next.Load() {
+			if i < offset {
+				i++
+				continue
+			}
+			pairs = append(pairs, Pair[K, V]{k: node.key, v: node.value})
+			if limit > 0 && int32(len(pairs)) >= limit {
+				break
+			}
+		}
+	})
+	return pairs
+}
+
+// CountByScore value在[min, max]区间的元素个数
+func (s *ConcurrentSkiplistT[K, V]) CountByScore(min, max V) int32 {
+	var first, last int32
+	s.read(func() {
+		_, first = s.firstNotLess(min)
+		last = s.lastNotGreater(max)
+	})
+	if last < first {
+		return 0
+	}
+	return last - first + 1
+}
+
+// RangeByScore 无锁遍历value在[min, max]区间的元素，f返回false则停止
+// 遍历是弱一致的：遍历期间一直存在的元素都会被访问到，期间插入或删除的元素可能被访问到也可能不会
+func (s *ConcurrentSkiplistT[K, V]) RangeByScore(min, max V, f func(key K, value V) bool) {
+	node, _ := s.firstNotLess(min)
+	for ; node != nil && !s.less(max, node.value); node = node.layers[0].next.Load() {
+		if !f(node.key, node.value) {
+			return
+		}
+	}
+}
+
+// GetLength ...
+func (s *ConcurrentSkiplistT[K, V]) GetLength() int32 {
+	return s.currLength.Load()
+}
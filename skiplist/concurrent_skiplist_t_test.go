@@ -0,0 +1,157 @@
+package skiplist
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentSkiplistT(t *testing.T) {
+	const (
+		stableNum  = 1000
+		churnBase  = 100000
+		writerNum  = 4
+		readerNum  = 8
+		writeLoops = 5000
+	)
+	var (
+		s    = NewConcurrentSkiplistT[int, int](func(a, b int) bool { return a < b }, func(a, b int) bool { return a == b })
+		wg   sync.WaitGroup
+		stop int32
+	)
+
+	// 分数在[0, stableNum)的元素始终不变，它们的排名不受其他写操作的影响
+	for i := 0; i < stableNum; i++ {
+		s.Insert(i, i)
+	}
+
+	for w := 0; w < writerNum; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(w)))
+			var inserted []int
+			for n := 0; n < writeLoops; n++ {
+				if len(inserted) > 0 && r.Intn(3) == 0 {
+					k := inserted[len(inserted)-1]
+					if !s.Delete(k, k) {
+						t.Errorf("delete key %v failed", k)
+						return
+					}
+					inserted = inserted[:len(inserted)-1]
+				} else {
+					k := churnBase + w*writeLoops + n
+					s.Insert(k, k)
+					inserted = append(inserted, k)
+				}
+			}
+			for _, k := range inserted {
+				s.Delete(k, k)
+			}
+		}(w)
+	}
+
+	var rg sync.WaitGroup
+	for g := 0; g < readerNum; g++ {
+		rg.Add(1)
+		go func(g int) {
+			defer rg.Done()
+			r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(g)))
+			for atomic.LoadInt32(&stop) == 0 {
+				k := r.Intn(stableNum)
+				if !s.Has(k, k) {
+					t.Errorf("stable key %v not found", k)
+					return
+				}
+				if rank := s.GetRank(k, k); rank != int32(k+1) {
+					t.Errorf("stable key %v rank %v", k, rank)
+					return
+				}
+				if k%100 == 0 {
+					if v, o := s.Get(k); !o || v != k {
+						t.Errorf("get stable key %v got %v %v", k, v, o)
+						return
+					}
+				}
+				if key, _, o := s.GetByRank(int32(k + 1)); !o || key != k {
+					t.Errorf("rank %v got key %v", k+1, key)
+					return
+				}
+				if c := s.CountByScore(0, stableNum-1); c != stableNum {
+					t.Errorf("count by score got %v", c)
+					return
+				}
+				pairs := s.GetByScoreRange(k, stableNum-1, 0, 10)
+				for i, p := range pairs {
+					if p.GetKey() != k+i {
+						t.Errorf("score range got key %v, expect %v", p.GetKey(), k+i)
+						return
+					}
+				}
+				last := -1
+				s.RangeByScore(0, churnBase*2, func(key, _ int) bool {
+					if key <= last {
+						t.Errorf("range by score not in order: %v after %v", key, last)
+						return false
+					}
+					last = key
+					return true
+				})
+			}
+		}(g)
+	}
+
+	wg.Wait()
+	atomic.StoreInt32(&stop, 1)
+	rg.Wait()
+
+	if s.GetLength() != stableNum {
+		t.Fatalf("length %v, expect %v", s.GetLength(), stableNum)
+	}
+	pairs := s.GetByRankRange(1, stableNum+1)
+	if len(pairs) != stableNum {
+		t.Fatalf("get by rank range length %v", len(pairs))
+	}
+	for i, p := range pairs {
+		if p.GetKey() != i {
+			t.Fatalf("rank %v got key %v", i+1, p.GetKey())
+		}
+	}
+	for i := stableNum - 1; i >= 0; i-- {
+		if k, _, o := s.DeleteTail(); !o || k != i {
+			t.Fatalf("delete tail got %v, expect %v", k, i)
+		}
+	}
+	if s.GetLength() != 0 || s.currLayer.Load() != 1 {
+		t.Fatalf("skiplist not empty after delete all")
+	}
+}
+
+func TestConcurrentSkiplistTReadFallback(t *testing.T) {
+	s := NewConcurrentSkiplistT[int, int](func(a, b int) bool { return a < b }, func(a, b int) bool { return a == b })
+	s.Insert(1, 1)
+	if _, o := s.Get(2); o {
+		t.Fatalf("get absent key")
+	}
+
+	// 写操作一直没有结束时，乐观读重试有限次后等待写锁，而不是一直自旋
+	s.beginWrite()
+	done := make(chan int32)
+	go func() {
+		done <- s.GetRank(1, 1)
+	}()
+	select {
+	case rank := <-done:
+		t.Fatalf("read returned %v during write", rank)
+	case <-time.After(20 * time.Millisecond):
+	}
+	s.endWrite()
+	if rank := <-done; rank != 1 {
+		t.Fatalf("rank after write got %v", rank)
+	}
+	if v, o := s.Get(1); !o || v != 1 {
+		t.Fatalf("get got %v %v", v, o)
+	}
+}
@@ -0,0 +1,486 @@
+package skiplist
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Pair ...
+type Pair[K, V any] struct {
+	k K
+	v V
+}
+
+// NewPair ...
+func NewPair[K, V any](key K, value V) Pair[K, V] {
+	return Pair[K, V]{k: key, v: value}
+}
+
+// GetKey ...
+func (pair Pair[K, V]) GetKey() K {
+	return pair.k
+}
+
+// GetValue ...
+func (pair Pair[K, V]) GetValue() V {
+	return pair.v
+}
+
+type skiplistLayerT[K, V any] struct {
+	next *skiplistItemT[K, V]
+	span int32
+}
+
+type skiplistItemT[K, V any] struct {
+	key      K
+	value    V
+	backward *skiplistItemT[K, V] // 第0层的前一个节点，第一个节点为nil
+	layers   []skiplistLayerT[K, V]
+}
+
+// SkiplistT 泛型跳表，按value排序，用key区分value相同的元素
+// less决定value的先后，value相同的元素按插入的先后排列
+type SkiplistT[K, V any] struct {
+	currLayer  int32
+	currLength int32
+	lengthsNum []int32                // 各层的节点数
+	head       *skiplistItemT[K, V]   // 头节点
+	tail       *skiplistItemT[K, V]   // 尾节点
+	beforeNode []*skiplistItemT[K, V] // 缓存插入之前或删除之前的节点
+	rank       []int32                // 缓存排名
+	rand       *rand.Rand             // 随机数
+	less       func(V, V) bool
+	equal      func(K, K) bool
+}
+
+// NewSkiplistT ...
+func NewSkiplistT[K, V any](less func(V, V) bool, equal func(K, K) bool) *SkiplistT[K, V] {
+	if less == nil || equal == nil {
+		panic("ponu.skiplist: SkiplistT need less and equal function")
+	}
+	return &SkiplistT[K, V]{
+		currLayer:  int32(1),
+		lengthsNum: make([]int32, MaxSkiplistLayer),
+		head:       newSkiplistItemT[K, V](MaxSkiplistLayer),
+		beforeNode: make([]*skiplistItemT[K, V], MaxSkiplistLayer),
+		rank:       make([]int32, MaxSkiplistLayer),
+		rand:       rand.New(rand.NewSource(time.Now().Unix())),
+		less:       less,
+		equal:      equal,
+	}
+}
+
+func newSkiplistItemT[K, V any](layer int32) *skiplistItemT[K, V] {
+	return &skiplistItemT[K, V]{
+		layers: make([]skiplistLayerT[K, V], layer),
+	}
+}
+
+func (s *SkiplistT[K, V]) randomSkiplistLayer() int32 {
+	n := int32(1)
+	r := s.rand.Int31()
+	for r%2 == 0 {
+		n++
+		r /= 2
+	}
+	if n > MaxSkiplistLayer {
+		n = MaxSkiplistLayer
+	}
+	return n
+}
+
+// Insert 插入元素，返回插入后的排名，不检查key是否已存在
+func (s *SkiplistT[K, V]) Insert(key K, value V) int32 {
+	node := s.head
+	for i := s.currLayer - 1; i >= 0; i-- {
+		if i == s.currLayer-1 {
+			s.rank[i] = 0
+		} else {
+			s.rank[i] = s.rank[i+1]
+		}
+		// value相同的插在后面
+		for node.layers[i].next != nil && !s.less(value, node.layers[i].next.value) {
+			s.rank[i] += node.layers[i].span
+			node = node.layers[i].next
+		}
+		s.beforeNode[i] = node
+	}
+
+	newLayer := s.randomSkiplistLayer()
+	if newLayer > s.currLayer {
+		for i := s.currLayer; i < newLayer; i++ {
+			s.rank[i] = 0
+			s.beforeNode[i] = s.head
+			s.head.layers[i].span = s.currLength
+		}
+		s.currLayer = newLayer
+	}
+
+	newNode := newSkiplistItemT[K, V](newLayer)
+	newNode.key = key
+	newNode.value = value
+	for i := int32(0); i < newLayer; i++ {
+		node = s.beforeNode[i]
+		newNode.layers[i].next = node.layers[i].next
+		node.layers[i].next = newNode
+		newNode.layers[i].span = node.layers[i].span - (s.rank[0] - s.rank[i])
+		node.layers[i].span = (s.rank[0] - s.rank[i]) + 1
+	}
+
+	for i := newLayer; i < s.currLayer; i++ {
+		s.beforeNode[i].layers[i].span++
+	}
+
+	if s.beforeNode[0] != s.head {
+		newNode.backward = s.beforeNode[0]
+	}
+	if newNode.layers[0].next != nil {
+		newNode.layers[0].next.backward = newNode
+	} else {
+		s.tail = newNode
+	}
+
+	s.lengthsNum[newLayer-1]++
+	s.currLength++
+
+	return s.rank[0] + 1
+}
+
+// getNode 查找元素，返回节点和排名
+func (s *SkiplistT[K, V]) getNode(key K, value V) (*skiplistItemT[K, V], int32) {
+	var rank int32
+	n := s.head
+	for i := s.currLayer - 1; i >= 0; i-- {
+		for n.layers[i].next != nil && s.less(n.layers[i].next.value, value) {
+			rank += n.layers[i].span
+			n = n.layers[i].next
+		}
+	}
+	// value相同的元素在第0层依次查找key
+	for n = n.layers[0].next; n != nil && !s.less(value, n.value); n = n.layers[0].next {
+		rank++
+		if s.equal(n.key, key) {
+			return n, rank
+		}
+	}
+	return nil, 0
+}
+
+// getNodeByRank ...
+func (s *SkiplistT[K, V]) getNodeByRank(rank int32) *skiplistItemT[K, V] {
+	if rank <= 0 || rank > s.currLength {
+		return nil
+	}
+	n := s.head
+	currRank := int32(0)
+	for i := s.currLayer - 1; i >= 0; i-- {
+		for n.layers[i].next != nil && (currRank+n.layers[i].span) <= rank {
+			currRank += n.layers[i].span
+			n = n.layers[i].next
+		}
+		if currRank == rank {
+			return n
+		}
+	}
+	return nil
+}
+
+// firstNotLess 第一个value不小于min的节点及其排名
+func (s *SkiplistT[K, V]) firstNotLess(min V) (*skiplistItemT[K, V], int32) {
+	var rank int32
+	n := s.head
+	for i := s.currLayer - 1; i >= 0; i-- {
+		for n.layers[i].next != nil && s.less(n.layers[i].next.value, min) {
+			rank += n.layers[i].span
+			n = n.layers[i].next
+		}
+	}
+	return n.layers[0].next, rank + 1
+}
+
+// lastNotGreater 最后一个value不大于max的节点及其排名
+func (s *SkiplistT[K, V]) lastNotGreater(max V) (*skiplistItemT[K, V], int32) {
+	var rank int32
+	n := s.head
+	for i := s.currLayer - 1; i >= 0; i-- {
+		for n.layers[i].next != nil && !s.less(max, n.layers[i].next.value) {
+			rank += n.layers[i].span
+			n = n.layers[i].next
+		}
+	}
+	if n == s.head {
+		return nil, 0
+	}
+	return n, rank
+}
+
+// deleteByRank 按排名找到各层删除位置之前的节点，再删除
+func (s *SkiplistT[K, V]) deleteByRank(rank int32) *skiplistItemT[K, V] {
+	if rank <= 0 || rank > s.currLength {
+		return nil
+	}
+	var currRank int32
+	n := s.head
+	for i := s.currLayer - 1; i >= 0; i-- {
+		for n.layers[i].next != nil && currRank+n.layers[i].span < rank {
+			currRank += n.layers[i].span
+			n = n.layers[i].next
+		}
+		s.beforeNode[i] = n
+	}
+	node := n.layers[0].next
+	s.deleteNode(node)
+	return node
+}
+
+func (s *SkiplistT[K, V]) deleteNode(node *skiplistItemT[K, V]) {
+	for i := int32(0); i < s.currLayer; i++ {
+		before := s.beforeNode[i]
+		if before.layers[i].next == node {
+			before.layers[i].span += node.layers[i].span - 1
+			before.layers[i].next = node.layers[i].next
+		} else {
+			before.layers[i].span--
+		}
+	}
+
+	if node.layers[0].next != nil {
+		node.layers[0].next.backward = node.backward
+	} else {
+		s.tail = node.backward
+	}
+
+	// 更新当前最大层数
+	for s.currLayer > 1 && s.head.layers[s.currLayer-1].next == nil {
+		s.currLayer--
+	}
+
+	s.lengthsNum[len(node.layers)-1]--
+	s.currLength--
+}
+
+// Delete ...
+func (s *SkiplistT[K, V]) Delete(key K, value V) bool {
+	_, rank := s.getNode(key, value)
+	if rank == 0 {
+		return false
+	}
+	s.deleteByRank(rank)
+	return true
+}
+
+// DeleteByRank ...
+func (s *SkiplistT[K, V]) DeleteByRank(rank int32) (K, V, bool) {
+	node := s.deleteByRank(rank)
+	if node == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	return node.key, node.value, true
+}
+
+// DeleteTail ...
+func (s *SkiplistT[K, V]) DeleteTail() (K, V, bool) {
+	return s.DeleteByRank(s.currLength)
+}
+
+// GetByRank ...
+func (s *SkiplistT[K, V]) GetByRank(rank int32) (K, V, bool) {
+	node := s.getNodeByRank(rank)
+	if node == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	return node.key, node.value, true
+}
+
+// GetRank 获取排名，从1开始，不存在返回0
+func (s *SkiplistT[K, V]) GetRank(key K, value V) int32 {
+	_, rank := s.getNode(key, value)
+	return rank
+}
+
+// GetByRankRange 从排名rankStart开始获取最多rankNum个元素填充到pairs，返回获取的个数
+func (s *SkiplistT[K, V]) GetByRankRange(rankStart, rankNum int32, pairs []Pair[K, V]) int32 {
+	if rankNum <= 0 || len(pairs) < int(rankNum) {
+		return 0
+	}
+	var n int32
+	for node := s.getNodeByRank(rankStart); node != nil && n < rankNum; node = node.layers[0].next {
+		pairs[n] = Pair[K, V]{k: node.key, v: node.value}
+		n++
+	}
+	return n
+}
+
+// RangeByRank 按排名遍历[rankStart, rankEnd]区间，f返回false则停止
+func (s *SkiplistT[K, V]) RangeByRank(rankStart, rankEnd int32, f func(rank int32, key K, value V) bool) {
+	if rankStart <= 0 {
+		rankStart = 1
+	}
+	rank := rankStart
+	for node := s.getNodeByRank(rankStart); node != nil && rank <= rankEnd; node = node.layers[0].next {
+		if !f(rank, node.key, node.value) {
+			return
+		}
+		rank++
+	}
+}
+
+// RangeByScore 按value从小到大遍历value在[min, max]区间的元素，f返回false则停止
+func (s *SkiplistT[K, V]) RangeByScore(min, max V, f func(rank int32, key K, value V) bool) {
+	node, rank := s.firstNotLess(min)
+	for ; node != nil && !s.less(max, node.value); node = node.layers[0].next {
+		if !f(rank, node.key, node.value) {
+			return
+		}
+		rank++
+	}
+}
+
+// RevRangeByScore 按value从大到小遍历value在[min, max]区间的元素，f返回false则停止
+func (s *SkiplistT[K, V]) RevRangeByScore(max, min V, f func(rank int32, key K, value V) bool) {
+	node, rank := s.lastNotGreater(max)
+	for ; node != nil && !s.less(node.value, min); node = node.backward {
+		if !f(rank, node.key, node.value) {
+			return
+		}
+		rank--
+	}
+}
+
+// GetByScoreRange 获取value在[min, max]区间的元素，跳过前offset个，最多获取limit个，limit小于0表示不限制
+func (s *SkiplistT[K, V]) GetByScoreRange(min, max V, offset, limit int32) []Pair[K, V] {
+	var pairs []Pair[K, V]
+	if limit == 0 {
+		return pairs
+	}
+	s.RangeByScore(min, max, func(_ int32, key K, value V) bool {
+		if offset > 0 {
+			offset--
+			return true
+		}
+		pairs = append(pairs, Pair[K, V]{k: key, v: value})
+		return limit < 0 || int32(len(pairs)) < limit
+	})
+	return pairs
+}
+
+// CountByScore value在[min, max]区间的元素个数
+func (s *SkiplistT[K, V]) CountByScore(min, max V) int32 {
+	_, first := s.firstNotLess(min)
+	_, last := s.lastNotGreater(max)
+	if last < first {
+		return 0
+	}
+	return last - first + 1
+}
+
+// CountLess value小于value的元素个数
+func (s *SkiplistT[K, V]) CountLess(value V) int32 {
+	_, rank := s.firstNotLess(value)
+	return rank - 1
+}
+
+// GetFirst ...
+func (s *SkiplistT[K, V]) GetFirst() (K, V, bool) {
+	return s.GetByRank(1)
+}
+
+// GetTail ...
+func (s *SkiplistT[K, V]) GetTail() (K, V, bool) {
+	if s.tail == nil {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	return s.tail.key, s.tail.value, true
+}
+
+// PullList ...
+func (s *SkiplistT[K, V]) PullList() []Pair[K, V] {
+	pairs := make([]Pair[K, V], 0, s.currLength)
+	for node := s.head.layers[0].next; node != nil; node = node.layers[0].next {
+		pairs = append(pairs, Pair[K, V]{k: node.key, v: node.value})
+	}
+	return pairs
+}
+
+// Clear ...
+func (s *SkiplistT[K, V]) Clear() {
+	s.head = newSkiplistItemT[K, V](MaxSkiplistLayer)
+	s.tail = nil
+	s.currLayer = 1
+	s.currLength = 0
+	for i := range s.lengthsNum {
+		s.lengthsNum[i] = 0
+	}
+}
+
+// LoadSorted 用已按value排好序的pairs替换跳表的内容，线性时间
+// pairs未排序时返回false，跳表不变
+func (s *SkiplistT[K, V]) LoadSorted(pairs []Pair[K, V]) bool {
+	for i := 1; i < len(pairs); i++ {
+		if s.less(pairs[i].v, pairs[i-1].v) {
+			return false
+		}
+	}
+
+	s.Clear()
+	// 每层的最后一个节点及其排名
+	for i := range s.beforeNode {
+		s.beforeNode[i] = s.head
+		s.rank[i] = 0
+	}
+	for n, pair := range pairs {
+		rank := int32(n + 1)
+		layer := s.randomSkiplistLayer()
+		if layer > s.currLayer {
+			s.currLayer = layer
+		}
+		node := newSkiplistItemT[K, V](layer)
+		node.key = pair.k
+		node.value = pair.v
+		for i := int32(0); i < layer; i++ {
+			s.beforeNode[i].layers[i].next = node
+			s.beforeNode[i].layers[i].span = rank - s.rank[i]
+			s.beforeNode[i] = node
+			s.rank[i] = rank
+		}
+		node.backward = s.tail
+		s.tail = node
+		s.lengthsNum[layer-1]++
+	}
+	s.currLength = int32(len(pairs))
+	// 每层最后一个节点的跨度到表尾
+	for i := int32(0); i < s.currLayer; i++ {
+		s.beforeNode[i].layers[i].span = s.currLength - s.rank[i]
+	}
+	return true
+}
+
+// GetLength ...
+func (s *SkiplistT[K, V]) GetLength() int32 {
+	return s.currLength
+}
+
+// GetLayer ...
+func (s *SkiplistT[K, V]) GetLayer() int32 {
+	return s.currLayer
+}
+
+// GetLayerLength ...
+func (s *SkiplistT[K, V]) GetLayerLength(layer int32) int32 {
+	if layer < 1 || layer > s.currLayer {
+		return -1
+	}
+	return s.lengthsNum[layer-1]
+}
@@ -0,0 +1,161 @@
+package skiplist
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func newIntSkiplistT() *SkiplistT[int, int] {
+	return NewSkiplistT[int, int](func(a, b int) bool { return a < b }, func(a, b int) bool { return a == b })
+}
+
+// checkSkiplistT 对照按value排序(value相同按插入先后)的切片校验跳表
+func checkSkiplistT(t *testing.T, s *SkiplistT[int, int], expect []Pair[int, int]) {
+	if s.GetLength() != int32(len(expect)) {
+		t.Fatalf("length %v not equal to %v", s.GetLength(), len(expect))
+	}
+	pairs := s.PullList()
+	for i, p := range pairs {
+		if p != expect[i] {
+			t.Fatalf("index %v got %+v, expect %+v", i, p, expect[i])
+		}
+	}
+	for i, p := range expect {
+		if r := s.GetRank(p.k, p.v); r != int32(i+1) {
+			t.Fatalf("key %v rank %v, expect %v", p.k, r, i+1)
+		}
+		if k, _, o := s.GetByRank(int32(i + 1)); !o || k != p.k {
+			t.Fatalf("rank %v got key %v, expect %v", i+1, k, p.k)
+		}
+	}
+	var i = len(expect) - 1
+	for node := s.tail; node != nil; node = node.backward {
+		if node.key != expect[i].k {
+			t.Fatalf("backward index %v got key %v, expect %v", i, node.key, expect[i].k)
+		}
+		i--
+	}
+	if i != -1 {
+		t.Fatalf("backward list length not match")
+	}
+}
+
+func TestSkiplistT(t *testing.T) {
+	var (
+		s      = newIntSkiplistT()
+		r      = rand.New(rand.NewSource(time.Now().Unix()))
+		expect []Pair[int, int]
+	)
+	for i := 0; i < 5000; i++ {
+		op := r.Intn(6)
+		if op == 0 && len(expect) > 0 {
+			n := r.Intn(len(expect))
+			p := expect[n]
+			if !s.Delete(p.k, p.v) {
+				t.Fatalf("delete key %v failed", p.k)
+			}
+			expect = append(expect[:n], expect[n+1:]...)
+		} else if op == 1 && len(expect) > 0 {
+			n := r.Intn(len(expect))
+			k, _, o := s.DeleteByRank(int32(n + 1))
+			if !o || k != expect[n].k {
+				t.Fatalf("delete by rank %v got key %v, expect %v", n+1, k, expect[n].k)
+			}
+			expect = append(expect[:n], expect[n+1:]...)
+		} else {
+			k := i
+			v := r.Intn(100)
+			rank := s.Insert(k, v)
+			pos := 0
+			for pos < len(expect) && expect[pos].v <= v {
+				pos++
+			}
+			if rank != int32(pos+1) {
+				t.Fatalf("insert key %v rank %v, expect %v", k, rank, pos+1)
+			}
+			expect = append(expect, Pair[int, int]{})
+			copy(expect[pos+1:], expect[pos:])
+			expect[pos] = Pair[int, int]{k: k, v: v}
+		}
+	}
+	checkSkiplistT(t, s, expect)
+
+	if s.Delete(-1, 0) {
+		t.Fatalf("delete absent key must fail")
+	}
+
+	var count int32
+	for _, p := range expect {
+		if p.v >= 20 && p.v <= 40 {
+			count++
+		}
+	}
+	if c := s.CountByScore(20, 40); c != count {
+		t.Fatalf("count by score got %v, expect %v", c, count)
+	}
+	var ranged []Pair[int, int]
+	s.RangeByScore(20, 40, func(rank int32, key, value int) bool {
+		if expect[rank-1].k != key {
+			t.Fatalf("range by score rank %v got key %v", rank, key)
+		}
+		ranged = append(ranged, Pair[int, int]{k: key, v: value})
+		return true
+	})
+	if int32(len(ranged)) != count {
+		t.Fatalf("range by score got %v, expect %v", len(ranged), count)
+	}
+	var rev []Pair[int, int]
+	s.RevRangeByScore(40, 20, func(rank int32, key, value int) bool {
+		if expect[rank-1].k != key {
+			t.Fatalf("rev range by score rank %v got key %v", rank, key)
+		}
+		rev = append(rev, Pair[int, int]{k: key, v: value})
+		return true
+	})
+	for i := range rev {
+		if rev[i] != ranged[len(ranged)-1-i] {
+			t.Fatalf("rev range by score not reverse of range by score")
+		}
+	}
+	if limited := s.GetByScoreRange(20, 40, 1, 2); count > 3 && (len(limited) != 2 || limited[0] != ranged[1]) {
+		t.Fatalf("get by score range with limit got %v", limited)
+	}
+
+	pairs := make([]Pair[int, int], 10)
+	if n := s.GetByRankRange(s.GetLength()-4, 10, pairs); n != 5 || pairs[4] != expect[len(expect)-1] {
+		t.Fatalf("get by rank range got %v", n)
+	}
+
+	for s.GetLength() > 0 {
+		if _, _, o := s.DeleteTail(); !o {
+			t.Fatalf("delete tail failed")
+		}
+	}
+	if s.GetLayer() != 1 || s.tail != nil {
+		t.Fatalf("skiplist not empty after delete all")
+	}
+}
+
+func TestSkiplistTLoadSorted(t *testing.T) {
+	s := newIntSkiplistT()
+	if s.LoadSorted([]Pair[int, int]{NewPair(1, 2), NewPair(2, 1)}) {
+		t.Fatalf("load unsorted pairs must fail")
+	}
+
+	var expect []Pair[int, int]
+	for i := 0; i < 1000; i++ {
+		expect = append(expect, NewPair(i, i/3))
+	}
+	s.Insert(-1, -1)
+	if !s.LoadSorted(expect) {
+		t.Fatalf("load sorted pairs failed")
+	}
+	checkSkiplistT(t, s, expect)
+
+	// 加载后的跳表可以继续正常插入删除
+	s.Insert(1000, 100)
+	s.Delete(0, 0)
+	expect = append(expect[1:303], append([]Pair[int, int]{NewPair(1000, 100)}, expect[303:]...)...)
+	checkSkiplistT(t, s, expect)
+}
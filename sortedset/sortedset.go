@@ -0,0 +1,343 @@
+package sortedset
+
+import (
+	"github.com/huoshan017/ponu/skiplist"
+	"golang.org/x/exp/constraints"
+)
+
+// Score 分数类型
+type Score interface {
+	constraints.Integer | constraints.Float
+}
+
+// AddFlag Add的选项，对应ZADD的NX/XX/GT/LT
+type AddFlag int32
+
+const (
+	AddNX AddFlag = 1 << iota // 只添加新成员，不更新已存在的成员
+	AddXX                     // 只更新已存在的成员，不添加新成员
+	AddGT                     // 已存在的成员只在新分数更大时更新
+	AddLT                     // 已存在的成员只在新分数更小时更新
+)
+
+const (
+	boundNegInf int8 = -2 // 负无穷
+	boundBefore int8 = -1 // 同分数的所有成员之前
+	boundMember int8 = 0  // 成员
+	boundAfter  int8 = 1  // 同分数的所有成员之后
+	boundPosInf int8 = 2  // 正无穷
+)
+
+// entry 跳表中的元素，bound不为boundMember时表示查询用的分数边界
+type entry[M constraints.Ordered, S Score] struct {
+	score  S
+	member M
+	bound  int8
+}
+
+func infClass(bound int8) int8 {
+	if bound == boundNegInf {
+		return -1
+	}
+	if bound == boundPosInf {
+		return 1
+	}
+	return 0
+}
+
+// entryLess 先比较分数，分数相同再比较成员
+func entryLess[M constraints.Ordered, S Score](a, b entry[M, S]) bool {
+	if ca, cb := infClass(a.bound), infClass(b.bound); ca != cb || ca != 0 {
+		return ca < cb
+	}
+	if a.score != b.score {
+		return a.score < b.score
+	}
+	if a.bound != b.bound {
+		return a.bound < b.bound
+	}
+	return a.member < b.member
+}
+
+// Bound 分数区间的边界
+type Bound[S Score] struct {
+	score     S
+	exclusive bool
+	inf       int8 // -1负无穷，1正无穷
+}
+
+// Inclusive 闭区间边界
+func Inclusive[S Score](score S) Bound[S] {
+	return Bound[S]{score: score}
+}
+
+// Exclusive 开区间边界
+func Exclusive[S Score](score S) Bound[S] {
+	return Bound[S]{score: score, exclusive: true}
+}
+
+// NegInf 负无穷
+func NegInf[S Score]() Bound[S] {
+	return Bound[S]{inf: -1}
+}
+
+// PosInf 正无穷
+func PosInf[S Score]() Bound[S] {
+	return Bound[S]{inf: 1}
+}
+
+// asMin 作为区间下界时对应的跳表元素
+func asMin[M constraints.Ordered, S Score](b Bound[S]) entry[M, S] {
+	if b.inf < 0 {
+		return entry[M, S]{bound: boundNegInf}
+	}
+	if b.inf > 0 {
+		return entry[M, S]{bound: boundPosInf}
+	}
+	if b.exclusive {
+		return entry[M, S]{score: b.score, bound: boundAfter}
+	}
+	return entry[M, S]{score: b.score, bound: boundBefore}
+}
+
+// asMax 作为区间上界时对应的跳表元素
+func asMax[M constraints.Ordered, S Score](b Bound[S]) entry[M, S] {
+	if b.inf < 0 {
+		return entry[M, S]{bound: boundNegInf}
+	}
+	if b.inf > 0 {
+		return entry[M, S]{bound: boundPosInf}
+	}
+	if b.exclusive {
+		return entry[M, S]{score: b.score, bound: boundBefore}
+	}
+	return entry[M, S]{score: b.score, bound: boundAfter}
+}
+
+// Element 成员和分数
+type Element[M constraints.Ordered, S Score] struct {
+	member M
+	score  S
+}
+
+// GetMember ...
+func (e Element[M, S]) GetMember() M {
+	return e.member
+}
+
+// GetScore ...
+func (e Element[M, S]) GetScore() S {
+	return e.score
+}
+
+// SortedSet 有序集合，按分数从小到大排列，分数相同按成员从小到大排列
+// 排名从1开始
+type SortedSet[M constraints.Ordered, S Score] struct {
+	list   *skiplist.SkiplistT[M, entry[M, S]]
+	scores map[M]S
+}
+
+// NewSortedSet ...
+func NewSortedSet[M constraints.Ordered, S Score]() *SortedSet[M, S] {
+	return &SortedSet[M, S]{
+		list:   skiplist.NewSkiplistT[M, entry[M, S]](entryLess[M, S], func(a, b M) bool { return a == b }),
+		scores: make(map[M]S),
+	}
+}
+
+// Len ...
+func (z *SortedSet[M, S]) Len() int32 {
+	return int32(len(z.scores))
+}
+
+// Score 获取成员的分数
+func (z *SortedSet[M, S]) Score(member M) (S, bool) {
+	s, o := z.scores[member]
+	return s, o
+}
+
+func (z *SortedSet[M, S]) set(member M, score S, old S, has bool) {
+	if has {
+		z.list.Delete(member, entry[M, S]{score: old, member: member})
+	}
+	z.list.Insert(member, entry[M, S]{score: score, member: member})
+	z.scores[member] = score
+}
+
+// Add 添加成员或更新成员的分数，返回集合是否有变化
+func (z *SortedSet[M, S]) Add(member M, score S, flags AddFlag) bool {
+	old, has := z.scores[member]
+	if has {
+		if flags&AddNX != 0 {
+			return false
+		}
+		if flags&AddGT != 0 && score <= old {
+			return false
+		}
+		if flags&AddLT != 0 && score >= old {
+			return false
+		}
+		if score == old {
+			return false
+		}
+	} else if flags&AddXX != 0 {
+		return false
+	}
+	z.set(member, score, old, has)
+	return true
+}
+
+// IncrBy 成员的分数增加delta，成员不存在则以delta为分数添加，返回新的分数
+func (z *SortedSet[M, S]) IncrBy(member M, delta S) S {
+	old, has := z.scores[member]
+	score := old + delta
+	if !has || score != old {
+		z.set(member, score, old, has)
+	}
+	return score
+}
+
+// Remove ...
+func (z *SortedSet[M, S]) Remove(member M) bool {
+	score, o := z.scores[member]
+	if !o {
+		return false
+	}
+	delete(z.scores, member)
+	z.list.Delete(member, entry[M, S]{score: score, member: member})
+	return true
+}
+
+// Rank 按分数从小到大的排名，从1开始，不存在返回0
+func (z *SortedSet[M, S]) Rank(member M) int32 {
+	score, o := z.scores[member]
+	if !o {
+		return 0
+	}
+	return z.list.GetRank(member, entry[M, S]{score: score, member: member})
+}
+
+// RevRank 按分数从大到小的排名，从1开始，不存在返回0
+func (z *SortedSet[M, S]) RevRank(member M) int32 {
+	rank := z.Rank(member)
+	if rank == 0 {
+		return 0
+	}
+	return z.Len() - rank + 1
+}
+
+// normalizeRange 把排名区间规范化到[1, Len()]，负数表示倒数，-1为最后一个
+func (z *SortedSet[M, S]) normalizeRange(start, stop int32) (int32, int32, bool) {
+	length := z.Len()
+	if start < 0 {
+		start += length + 1
+	}
+	if stop < 0 {
+		stop += length + 1
+	}
+	if start < 1 {
+		start = 1
+	}
+	if stop > length {
+		stop = length
+	}
+	return start, stop, start <= stop
+}
+
+// RangeByRank 获取按分数从小到大排名在[start, stop]区间的成员
+func (z *SortedSet[M, S]) RangeByRank(start, stop int32) []Element[M, S] {
+	start, stop, o := z.normalizeRange(start, stop)
+	if !o {
+		return nil
+	}
+	elements := make([]Element[M, S], 0, stop-start+1)
+	z.list.RangeByRank(start, stop, func(_ int32, member M, e entry[M, S]) bool {
+		elements = append(elements, Element[M, S]{member: member, score: e.score})
+		return true
+	})
+	return elements
+}
+
+// RevRangeByRank 获取按分数从大到小排名在[start, stop]区间的成员
+func (z *SortedSet[M, S]) RevRangeByRank(start, stop int32) []Element[M, S] {
+	start, stop, o := z.normalizeRange(start, stop)
+	if !o {
+		return nil
+	}
+	length := z.Len()
+	elements := z.RangeByRank(length-stop+1, length-start+1)
+	for i, j := 0, len(elements)-1; i < j; i, j = i+1, j-1 {
+		elements[i], elements[j] = elements[j], elements[i]
+	}
+	return elements
+}
+
+// RangeByScore 按分数从小到大获取分数在[min, max]区间的成员，跳过前offset个，最多获取limit个，limit小于0表示不限制
+func (z *SortedSet[M, S]) RangeByScore(min, max Bound[S], offset, limit int32) []Element[M, S] {
+	var elements []Element[M, S]
+	if limit == 0 {
+		return elements
+	}
+	z.list.RangeByScore(asMin[M](min), asMax[M](max), func(_ int32, member M, e entry[M, S]) bool {
+		if offset > 0 {
+			offset--
+			return true
+		}
+		elements = append(elements, Element[M, S]{member: member, score: e.score})
+		return limit < 0 || int32(len(elements)) < limit
+	})
+	return elements
+}
+
+// RevRangeByScore 按分数从大到小获取分数在[min, max]区间的成员，跳过前offset个，最多获取limit个，limit小于0表示不限制
+func (z *SortedSet[M, S]) RevRangeByScore(max, min Bound[S], offset, limit int32) []Element[M, S] {
+	var elements []Element[M, S]
+	if limit == 0 {
+		return elements
+	}
+	z.list.RevRangeByScore(asMax[M](max), asMin[M](min), func(_ int32, member M, e entry[M, S]) bool {
+		if offset > 0 {
+			offset--
+			return true
+		}
+		elements = append(elements, Element[M, S]{member: member, score: e.score})
+		return limit < 0 || int32(len(elements)) < limit
+	})
+	return elements
+}
+
+// Count 分数在[min, max]区间的成员个数
+func (z *SortedSet[M, S]) Count(min, max Bound[S]) int32 {
+	return z.list.CountByScore(asMin[M](min), asMax[M](max))
+}
+
+// RemRangeByRank 删除按分数从小到大排名在[start, stop]区间的成员，返回删除的个数
+func (z *SortedSet[M, S]) RemRangeByRank(start, stop int32) int32 {
+	start, stop, o := z.normalizeRange(start, stop)
+	if !o {
+		return 0
+	}
+	for i := start; i <= stop; i++ {
+		member, _, _ := z.list.DeleteByRank(start)
+		delete(z.scores, member)
+	}
+	return stop - start + 1
+}
+
+// PopMin 删除并返回分数最小的成员
+func (z *SortedSet[M, S]) PopMin() (M, S, bool) {
+	member, e, o := z.list.DeleteByRank(1)
+	if o {
+		delete(z.scores, member)
+	}
+	return member, e.score, o
+}
+
+// PopMax 删除并返回分数最大的成员
+func (z *SortedSet[M, S]) PopMax() (M, S, bool) {
+	member, e, o := z.list.DeleteTail()
+	if o {
+		delete(z.scores, member)
+	}
+	return member, e.score, o
+}
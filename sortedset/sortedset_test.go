@@ -0,0 +1,94 @@
+package sortedset
+
+import (
+	"testing"
+)
+
+func members[S Score](elements []Element[string, S]) []string {
+	var ms []string
+	for _, e := range elements {
+		ms = append(ms, e.GetMember())
+	}
+	return ms
+}
+
+func equalMembers(t *testing.T, name string, got, expect []string) {
+	if len(got) != len(expect) {
+		t.Fatalf("%v got %v, expect %v", name, got, expect)
+	}
+	for i := range got {
+		if got[i] != expect[i] {
+			t.Fatalf("%v got %v, expect %v", name, got, expect)
+		}
+	}
+}
+
+func TestSortedSetAdd(t *testing.T) {
+	z := NewSortedSet[string, int64]()
+	if !z.Add("a", 10, 0) || !z.Add("b", 20, 0) || !z.Add("c", 20, 0) {
+		t.Fatalf("add new members failed")
+	}
+	if z.Add("a", 5, AddNX) || z.Add("d", 5, AddXX) {
+		t.Fatalf("NX/XX flag not work")
+	}
+	if z.Add("a", 5, AddGT) || !z.Add("a", 15, AddGT) {
+		t.Fatalf("GT flag not work")
+	}
+	if z.Add("a", 18, AddLT) || !z.Add("a", 1, AddLT) {
+		t.Fatalf("LT flag not work")
+	}
+	if s, _ := z.Score("a"); s != 1 {
+		t.Fatalf("score of a is %v", s)
+	}
+	if s := z.IncrBy("a", 100); s != 101 {
+		t.Fatalf("incr by got %v", s)
+	}
+	if s := z.IncrBy("e", 3); s != 3 || z.Len() != 4 {
+		t.Fatalf("incr by new member got %v", s)
+	}
+	// e:3 b:20 c:20 a:101
+	if z.Rank("e") != 1 || z.Rank("b") != 2 || z.Rank("c") != 3 || z.Rank("a") != 4 {
+		t.Fatalf("rank not match")
+	}
+	if z.RevRank("a") != 1 || z.RevRank("e") != 4 || z.Rank("x") != 0 {
+		t.Fatalf("rev rank not match")
+	}
+	if !z.Remove("b") || z.Remove("b") || z.Len() != 3 || z.Rank("c") != 2 {
+		t.Fatalf("remove failed")
+	}
+}
+
+func TestSortedSetRange(t *testing.T) {
+	z := NewSortedSet[string, float64]()
+	for i, m := range []string{"a", "b", "c", "d", "e", "f"} {
+		z.Add(m, float64(i/2), 0) // a:0 b:0 c:1 d:1 e:2 f:2
+	}
+
+	equalMembers(t, "RangeByRank", members(z.RangeByRank(2, 4)), []string{"b", "c", "d"})
+	equalMembers(t, "RangeByRank negative", members(z.RangeByRank(-2, -1)), []string{"e", "f"})
+	equalMembers(t, "RevRangeByRank", members(z.RevRangeByRank(1, 3)), []string{"f", "e", "d"})
+
+	equalMembers(t, "RangeByScore inclusive", members(z.RangeByScore(Inclusive(0.0), Inclusive(1.0), 0, -1)), []string{"a", "b", "c", "d"})
+	equalMembers(t, "RangeByScore exclusive min", members(z.RangeByScore(Exclusive(0.0), Inclusive(2.0), 0, -1)), []string{"c", "d", "e", "f"})
+	equalMembers(t, "RangeByScore exclusive max", members(z.RangeByScore(NegInf[float64](), Exclusive(1.0), 0, -1)), []string{"a", "b"})
+	equalMembers(t, "RangeByScore limit", members(z.RangeByScore(NegInf[float64](), PosInf[float64](), 1, 3)), []string{"b", "c", "d"})
+	equalMembers(t, "RevRangeByScore", members(z.RevRangeByScore(Exclusive(2.0), Inclusive(0.0), 1, 2)), []string{"c", "b"})
+	if c := z.Count(Inclusive(0.5), PosInf[float64]()); c != 4 {
+		t.Fatalf("count got %v", c)
+	}
+
+	if n := z.RemRangeByRank(2, 3); n != 2 {
+		t.Fatalf("rem range by rank got %v", n)
+	}
+	equalMembers(t, "after RemRangeByRank", members(z.RangeByRank(1, -1)), []string{"a", "d", "e", "f"})
+
+	if m, s, o := z.PopMin(); !o || m != "a" || s != 0 {
+		t.Fatalf("pop min got %v %v", m, s)
+	}
+	if m, s, o := z.PopMax(); !o || m != "f" || s != 2 {
+		t.Fatalf("pop max got %v %v", m, s)
+	}
+	if z.Len() != 2 || z.Rank("d") != 1 {
+		t.Fatalf("length after pop %v", z.Len())
+	}
+}
@@ -19,3 +19,20 @@ type IWheel interface {
 	Run()
 	Stop()
 }
+
+// Scheduler 添加定时器，SWheel、Wheel、Sender、Requester都满足
+// 使用定时器的模块(比如缓存的过期回收、排行榜的窗口滚动)只依赖这个接口
+type Scheduler interface {
+	Add(timeout time.Duration, fun TimerFunc, args []any) uint32
+}
+
+// CancelTimer 取消scheduler添加的定时器，SWheel的Cancel返回bool，和其他的签名不同，这里统一处理
+// scheduler不支持取消时什么都不做
+func CancelTimer(scheduler Scheduler, id uint32) {
+	switch s := scheduler.(type) {
+	case interface{ Cancel(uint32) bool }:
+		s.Cancel(id)
+	case interface{ Cancel(uint32) }:
+		s.Cancel(id)
+	}
+}
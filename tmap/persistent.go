@@ -0,0 +1,88 @@
+package tmap
+
+import (
+	"sync/atomic"
+
+	"github.com/huoshan017/ponu/rbtree"
+	"golang.org/x/exp/constraints"
+)
+
+// Snapshot PersistentTMap某一时刻的只读快照
+type Snapshot[K, V any] struct {
+	t *rbtree.PersistentRBTree[K, V]
+}
+
+func (this Snapshot[K, V]) Get(key K) (V, bool) {
+	return this.t.Get(key)
+}
+
+func (this Snapshot[K, V]) Has(key K) bool {
+	return this.t.Has(key)
+}
+
+func (this Snapshot[K, V]) Len() int32 {
+	return this.t.Len()
+}
+
+func (this Snapshot[K, V]) First() (K, V, bool) {
+	return this.t.Min()
+}
+
+func (this Snapshot[K, V]) Last() (K, V, bool) {
+	return this.t.Max()
+}
+
+func (this Snapshot[K, V]) Each(f func(key K, value V) bool) {
+	this.t.Each(f)
+}
+
+func (this Snapshot[K, V]) Range(from, to K, f func(key K, value V) bool) {
+	this.t.Range(from, to, f)
+}
+
+// PersistentTMap 以持久化红黑树为存储的TMap
+// 只允许一个goroutine写，其他goroutine通过Snapshot无锁读
+type PersistentTMap[K, V any] struct {
+	t atomic.Pointer[rbtree.PersistentRBTree[K, V]]
+}
+
+func NewPersistentTMap[K constraints.Ordered, V any]() *PersistentTMap[K, V] {
+	m := &PersistentTMap[K, V]{}
+	m.t.Store(rbtree.NewPersistentRBTree[K, V]())
+	return m
+}
+
+func NewPersistentTMapWithLess[K, V any](less func(K, K) bool) *PersistentTMap[K, V] {
+	m := &PersistentTMap[K, V]{}
+	m.t.Store(rbtree.NewPersistentRBTreeWithLess[K, V](less))
+	return m
+}
+
+func (this *PersistentTMap[K, V]) Insert(key K, value V) {
+	this.t.Store(this.t.Load().Insert(key, value))
+}
+
+func (this *PersistentTMap[K, V]) Delete(key K) bool {
+	t, o := this.t.Load().Delete(key)
+	if o {
+		this.t.Store(t)
+	}
+	return o
+}
+
+func (this *PersistentTMap[K, V]) Get(key K) (V, bool) {
+	return this.t.Load().Get(key)
+}
+
+func (this *PersistentTMap[K, V]) Has(key K) bool {
+	return this.t.Load().Has(key)
+}
+
+func (this *PersistentTMap[K, V]) Len() int32 {
+	return this.t.Load().Len()
+}
+
+// Snapshot 获取当前数据的快照，O(1)，之后的修改不会影响快照
+func (this *PersistentTMap[K, V]) Snapshot() Snapshot[K, V] {
+	return Snapshot[K, V]{t: this.t.Load()}
+}
@@ -1,134 +1,98 @@
 package tmap
 
 import (
-	"fmt"
-	"os"
-
 	"github.com/huoshan017/ponu/rbtree"
+	"golang.org/x/exp/constraints"
 )
 
-type KeyValue struct {
-	key   interface{}
-	value interface{}
-}
-
-func _get_value_from(i interface{}) (t int8, v1 int64, v2 uint64, v3 float32, v4 float64) {
-	switch tt := i.(type) {
-	case int8:
-		v1 = int64(i.(int8))
-	case uint8:
-		v1 = int64(i.(uint8))
-	case int16:
-		v1 = int64(i.(int16))
-	case uint16:
-		v1 = int64(i.(uint16))
-	case int32:
-		v1 = int64(i.(int32))
-	case uint32:
-		v1 = int64(i.(uint32))
-	case int:
-		v1 = int64(i.(int))
-	case uint:
-		v1 = int64(i.(uint))
-	case int64:
-		v1 = i.(int64)
-	case uint64:
-		v2 = i.(uint64)
-		t = 1
-	case float32:
-		v3 = i.(float32)
-		t = 2
-	case float64:
-		v4 = i.(float64)
-		t = 3
-	default:
-		fmt.Fprintf(os.Stderr, "unsupported type %v get value from", tt)
-		t = -1
-	}
-	return t, v1, v2, v3, v4
+// TMap 按key有序的map
+type TMap[K, V any] struct {
+	t    *rbtree.RBTreeT[K, V]
+	less func(K, K) bool
 }
 
-func (this *KeyValue) Less(node rbtree.NodeValue) bool {
-	n, b := node.(*KeyValue)
-	if !b {
-		fmt.Fprintf(os.Stderr, "tmap: cant convert rbtree.NodeValue to *KeyValue")
-		return false
-	}
+func NewTMap[K constraints.Ordered, V any]() *TMap[K, V] {
+	return NewTMapWithLess[K, V](func(a, b K) bool { return a < b })
+}
 
-	kt, kv1, kv2, kv3, kv4 := _get_value_from(this.key)
-	nt, nv1, nv2, nv3, nv4 := _get_value_from(n.key)
-
-	if kt < 0 || nt < 0 {
-		if func() int8 {
-			if kt < 0 {
-				return kt
-			} else {
-				return nt
-			}
-		}() < 0 {
-			fmt.Fprintf(os.Stderr, "tmap: unsupported type %v to compare")
-			return false
-		}
+func NewTMapWithLess[K, V any](less func(K, K) bool) *TMap[K, V] {
+	return &TMap[K, V]{
+		t:    rbtree.NewRBTreeTWithLess[K, V](less),
+		less: less,
 	}
+}
 
-	if kt != nt {
-		fmt.Fprintf(os.Stderr, "tmap: compare with different")
-		return false
-	}
+func (this *TMap[K, V]) Insert(key K, value V) {
+	this.t.Insert(key, value)
+}
+
+func (this *TMap[K, V]) Delete(key K) bool {
+	return this.t.Delete(key)
+}
 
-	if kt == 0 {
-		if kv1 < nv1 {
-			return true
-		}
-	} else if kt == 1 {
-		if kv2 < nv2 {
-			return true
-		}
-	} else if kt == 2 {
-		if kv3 < nv3 {
-			return true
-		}
-	} else if kt == 3 {
-		if kv4 < nv4 {
-			return true
-		}
+// DeleteRange 删除key在[from, to]区间内的元素，返回删除的个数，from大于to时不删除
+func (this *TMap[K, V]) DeleteRange(from, to K) int32 {
+	if this.less(to, from) {
+		return 0
 	}
+	var (
+		n    int32
+		iter = this.t.LowerBound(from)
+		end  = this.t.UpperBound(to)
+	)
+	for iter.IsValid() && iter != end {
+		iter = this.t.DeleteIter(iter)
+		n += 1
+	}
+	return n
+}
 
-	return false
+func (this *TMap[K, V]) Get(key K) (V, bool) {
+	return this.t.Get(key)
 }
 
-type TMap struct {
-	t rbtree.RBTree
+func (this *TMap[K, V]) Has(key K) bool {
+	return this.t.Has(key)
 }
 
-func (this *TMap) Insert(key, value interface{}) {
-	key_value := &KeyValue{
-		key:   key,
-		value: value,
-	}
-	this.t.Insert(key_value)
+func (this *TMap[K, V]) Len() int32 {
+	return this.t.Len()
 }
 
-func (this *TMap) Delete(key interface{}) bool {
-	return this.t.Delete(&KeyValue{
-		key: key,
-	})
+func (this *TMap[K, V]) Clear() {
+	this.t.Clear()
 }
 
-func (this *TMap) Get(key interface{}) interface{} {
-	var key_value = KeyValue{
-		key: key,
-	}
-	kv := this.t.Get(&key_value)
-	if kv == nil {
-		return nil
+func (this *TMap[K, V]) First() (K, V, bool) {
+	return this.t.Min()
+}
+
+func (this *TMap[K, V]) Last() (K, V, bool) {
+	return this.t.Max()
+}
+
+// Each 按key从小到大遍历，f返回false则停止
+func (this *TMap[K, V]) Each(f func(key K, value V) bool) {
+	this.t.Each(f)
+}
+
+// Range 按key从小到大遍历[from, to]区间，f返回false则停止
+func (this *TMap[K, V]) Range(from, to K, f func(key K, value V) bool) {
+	this.t.Range(from, to, f)
+}
+
+func (this *TMap[K, V]) Keys() []K {
+	keys := make([]K, 0, this.t.Len())
+	for iter := this.t.Begin(); iter.IsValid(); iter = iter.Next() {
+		keys = append(keys, iter.Key())
 	}
-	return (kv.(*KeyValue)).value
+	return keys
 }
 
-func (this *TMap) Has(key interface{}) bool {
-	var kv = KeyValue{
-		key: key,
+func (this *TMap[K, V]) Values() []V {
+	values := make([]V, 0, this.t.Len())
+	for iter := this.t.Begin(); iter.IsValid(); iter = iter.Next() {
+		values = append(values, iter.Value())
 	}
-	return this.t.Has(&kv)
+	return values
 }
@@ -6,12 +6,84 @@ import (
 
 func Test_one(t *testing.T) {
 	var key_value_list = []int{1, 1, 2, 2, 3, 3, 4, 4, 5, 5, 6, 6, 7, 7, 8, 8, 9, 9, 10, 10, 11, 11, 12, 12, 13, 13, 14, 14, 15, 15, 16, 16, 17, 17, 18, 18, 19, 19, 20, 20, 21, 21, 22, 22, 23, 23, 24, 24}
-	var m TMap
+	m := NewTMap[int, uint64]()
 	for i := 0; i < len(key_value_list)/2; i++ {
 		m.Insert(key_value_list[2*i], uint64(key_value_list[2*i+1]))
 	}
 	for i := 0; i < len(key_value_list)/2; i++ {
-		v := m.Get(key_value_list[2*i])
+		v, _ := m.Get(key_value_list[2*i])
 		t.Logf("key is %v, value is %v", key_value_list[2*i], v)
 	}
 }
+
+func Test_order(t *testing.T) {
+	m := NewTMap[int, string]()
+	for _, k := range []int{5, 3, 9, 1, 7} {
+		m.Insert(k, "")
+	}
+	keys := m.Keys()
+	if len(keys) != 5 || keys[0] != 1 || keys[4] != 9 {
+		t.Fatalf("keys not in order: %v", keys)
+	}
+	if k, _, _ := m.First(); k != 1 {
+		t.Fatalf("first key %v", k)
+	}
+	if k, _, _ := m.Last(); k != 9 {
+		t.Fatalf("last key %v", k)
+	}
+	if n := m.DeleteRange(7, 3); n != 0 || m.Len() != 5 {
+		t.Fatalf("inverted delete range count %v, len %v", n, m.Len())
+	}
+	if n := m.DeleteRange(2, 7); n != 3 {
+		t.Fatalf("delete range count %v", n)
+	}
+	if keys = m.Keys(); len(keys) != 2 || keys[0] != 1 || keys[1] != 9 || m.Len() != 2 {
+		t.Fatalf("after delete range keys %v", keys)
+	}
+
+	d := NewTMapWithLess[string, int](func(a, b string) bool { return a > b })
+	d.Insert("a", 1)
+	d.Insert("c", 3)
+	d.Insert("b", 2)
+	if values := d.Values(); values[0] != 3 || values[1] != 2 || values[2] != 1 {
+		t.Fatalf("values not in descending order: %v", values)
+	}
+}
+
+func Test_persistent(t *testing.T) {
+	m := NewPersistentTMap[int, int]()
+	for i := 0; i < 100; i++ {
+		m.Insert(i, i*10)
+	}
+	s := m.Snapshot()
+	for i := 0; i < 100; i += 2 {
+		m.Delete(i)
+	}
+	m.Insert(1, 100)
+	if v, _ := s.Get(1); s.Len() != 100 || v != 10 || !s.Has(0) {
+		t.Fatalf("snapshot changed after modify")
+	}
+	if v, _ := m.Get(1); m.Has(0) || v != 100 || m.Len() != 50 {
+		t.Fatalf("persistent tmap modify failed")
+	}
+	var last = -1
+	s.Each(func(key, value int) bool {
+		if key <= last {
+			t.Fatalf("snapshot each not in order")
+		}
+		last = key
+		return true
+	})
+}
+
+func Benchmark_get(b *testing.B) {
+	m := NewTMap[int, int]()
+	for i := 0; i < 10000; i++ {
+		m.Insert(i, i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Get(i % 10000)
+	}
+}